@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	qrcode "github.com/skip2/go-qrcode"
+	"github.com/tyler-smith/go-bip39"
+)
+
+const (
+	// identityBundleFormat tags the exported identity files
+	identityBundleFormat  = "gofarmer-identity"
+	identityBundleVersion = 1
+
+	// paperBackupQRSize size in pixels of the QR code of the paper backup
+	paperBackupQRSize = 320
+)
+
+// identityBundle is everything needed to restore an identity
+type identityBundle struct {
+	Mnemonic   string `json:"mnemonic"`
+	ThreebotID int64  `json:"threebotid"`
+	Network    string `json:"network"`
+	Explorer   string `json:"explorer"`
+	PublicKey  string `json:"pubkey"`
+}
+
+// exportedIdentity is the file written by exportIdentity, the bundle is
+// sealed like an encrypted seed file and the 3Bot ID, public key and
+// network are kept in clear
+type exportedIdentity struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	Network string `json:"network"`
+	encryptedSeed
+}
+
+// newIdentityBundle collects the identity ui registered on network n
+func newIdentityBundle(ui *UserIdentity, n Network) (identityBundle, error) {
+	if ui.Key().PrivateKey == nil {
+		return identityBundle{}, fmt.Errorf("no identity loaded")
+	}
+
+	mnemonic, err := bip39.NewMnemonic(ui.Key().PrivateKey.Seed())
+	if err != nil {
+		return identityBundle{}, err
+	}
+
+	return identityBundle{
+		Mnemonic:   mnemonic,
+		ThreebotID: ui.ThreebotID,
+		Network:    n.Key(),
+		Explorer:   n.URL,
+		PublicKey:  hex.EncodeToString(ui.Key().PublicKey),
+	}, nil
+}
+
+// exportIdentity returns the identity ui encrypted with passphrase
+func exportIdentity(ui *UserIdentity, n Network, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("a passphrase is required to export an identity")
+	}
+
+	bundle, err := newIdentityBundle(ui, n)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := newEncryptedSeed(payload, passphrase, bundle.ThreebotID, bundle.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(exportedIdentity{
+		Format:        identityBundleFormat,
+		Version:       identityBundleVersion,
+		Network:       bundle.Network,
+		encryptedSeed: sealed,
+	}, "", "  ")
+}
+
+// importIdentity opens an identity exported with exportIdentity and verifies
+// it against the explorer of network n before returning it, the caller is
+// responsible for saving it
+func importIdentity(n Network, data []byte, passphrase string, opts ...ClientOption) (*UserIdentity, error) {
+	var exported exportedIdentity
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return nil, errors.Wrap(err, "invalid identity file")
+	}
+	if exported.Format != identityBundleFormat {
+		return nil, fmt.Errorf("not a gofarmer identity file")
+	}
+	if exported.Version != identityBundleVersion {
+		return nil, fmt.Errorf("unsupported identity file version %d", exported.Version)
+	}
+	if exported.Network != n.Key() {
+		return nil, fmt.Errorf("identity was exported from network %s, switch to it before importing", exported.Network)
+	}
+
+	payload, err := exported.open(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	var bundle identityBundle
+	if err := json.Unmarshal(payload, &bundle); err != nil {
+		return nil, errors.Wrap(err, "invalid identity file")
+	}
+
+	ui, err := verifyIdentity(n, bundle.Mnemonic, bundle.ThreebotID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(hex.EncodeToString(ui.Key().PublicKey), bundle.PublicKey) {
+		return nil, fmt.Errorf("public key of the identity file doesn't match its words")
+	}
+
+	ui.Mnemonic = bundle.Mnemonic
+	return ui, nil
+}
+
+var paperBackupTemplate = template.Must(template.New("paper").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>3Bot {{.ThreebotID}} paper backup</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td { padding: 0.3em 1.2em 0.3em 0; font-family: monospace; font-size: 1.1em; }
+.key { word-break: break-all; font-family: monospace; }
+.warning { border: 1px solid #000; padding: 0.5em; }
+</style>
+</head>
+<body>
+<h1>3Bot identity paper backup</h1>
+<p class="warning">anyone holding these words controls your 3Bot and your farms, keep this page somewhere safe and never share it</p>
+<p>3Bot ID: <b>{{.ThreebotID}}</b></p>
+<p>Network: {{.Network}} ({{.Explorer}})</p>
+<p>Public key: <span class="key">{{.PublicKey}}</span></p>
+<p>Created: {{.Created}}</p>
+<h2>Words</h2>
+<table>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+<h2>QR code</h2>
+<p>the QR code holds the words, 3Bot ID and network in json</p>
+<img alt="identity QR code" src="{{.QRCode}}">
+</body>
+</html>
+`))
+
+// paperBackup renders a printable html page of the identity ui with its words
+// and a QR code holding the whole bundle, everything is generated locally
+func paperBackup(ui *UserIdentity, n Network) ([]byte, error) {
+	bundle, err := newIdentityBundle(ui, n)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	png, err := qrcode.Encode(string(payload), qrcode.Medium, paperBackupQRSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate QR code")
+	}
+
+	// words are numbered and shown 4 per row
+	var rows [][]string
+	for i, word := range strings.Fields(bundle.Mnemonic) {
+		if i%4 == 0 {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], fmt.Sprintf("%2d. %s", i+1, word))
+	}
+
+	var buf bytes.Buffer
+	err = paperBackupTemplate.Execute(&buf, struct {
+		identityBundle
+		Created string
+		Rows    [][]string
+		QRCode  template.URL
+	}{
+		identityBundle: bundle,
+		Created:        time.Now().Format("2006-01-02 15:04"),
+		Rows:           rows,
+		QRCode:         template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(png)),
+	})
+	return buf.Bytes(), err
+}
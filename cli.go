@@ -0,0 +1,1476 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Exit codes returned by the command line interface, scripts can rely on
+// them to tell the kind of failure apart without parsing the output
+const (
+	exitOK = iota
+	// exitFailure the explorer or the local filesystem returned an error
+	exitFailure
+	// exitUsage the command line could not be parsed
+	exitUsage
+	// exitNoIdentity the command needs a registered identity and none was found
+	exitNoIdentity
+	// exitInvalid the provided data failed validation
+	exitInvalid
+)
+
+type cliCommand struct {
+	usage string
+	run   func(args []string) int
+}
+
+var cliCommands = map[string]map[string]cliCommand{
+	"identity": {
+		"register": {"-name NAME.3bot -email EMAIL [-words WORDS] [-encrypt] [-force]", cmdIdentityRegister},
+		"show":     {"[-json]", cmdIdentityShow},
+		"encrypt":  {"", cmdIdentityEncrypt},
+		"convert":  {"-seed LEGACY_SEED [-id 3BOT_ID] [-encrypt] [-force]", cmdIdentityConvert},
+		"recover":  {"-words WORDS [-encrypt] [-force]", cmdIdentityRecover},
+		"export":   {"-out FILE | -paper FILE", cmdIdentityExport},
+		"import":   {"-in FILE [-encrypt] [-force]", cmdIdentityImport},
+	},
+	"farm": {
+		"create":    {"-name NAME -address TFT_ADDRESS [-email EMAIL] [-wallet ASSET:ADDRESS]... [-city CITY] [-country COUNTRY] [-lat N] [-long N]", cmdFarmCreate},
+		"update":    {"-id FARM_ID [-name NAME] [-address TFT_ADDRESS] [-email EMAIL] [-wallet ASSET:ADDRESS]... [-remove-wallet ASSETS] [-derive-location] [-city CITY] [-country COUNTRY] [-lat N] [-long N] [-dry-run]", cmdFarmUpdate},
+		"list":      {"[-owner 3BOT_ID] [-json]", cmdFarmList},
+		"transfer":  {"-id FARM_ID -to 3BOT_ID [-confirm FARM_NAME]", cmdFarmTransfer},
+		"transfers": {"[-json]", cmdFarmTransfers},
+		"capacity":  {"-id FARM_ID [-json]", cmdFarmCapacity},
+		"pricing":   {"-id FARM_ID [-custom=true|false] [-grid3=true|false] [-currency CURRENCY] [-cu N] [-su N] [-nu N] [-ipv4u N] [-cru N] [-mru N] [-hru N] [-sru N] [-nru N] [-dry-run] [-json]", cmdFarmPricing},
+	},
+	"ip": {
+		"list":   {"-farm FARM_ID [-json]", cmdIPList},
+		"add":    {"-farm FARM_ID -address IP/PREFIX -gateway GATEWAY [-last LAST_IP]", cmdIPAdd},
+		"remove": {"-farm FARM_ID IP/PREFIX", cmdIPRemove},
+	},
+	"profile": {
+		"list":   {"[-json]", cmdProfileList},
+		"use":    {"NAME", cmdProfileUse},
+		"import": {"-name NAME -words WORDS -id 3BOT_ID", cmdProfileImport},
+		"remove": {"[-force] NAME", cmdProfileRemove},
+	},
+	"node": {
+		"list":   {"-farm FARM_ID [-json]", cmdNodeList},
+		"show":   {"-farm FARM_ID [-proofs] [-json] NODE_ID", cmdNodeShow},
+		"public": {"[-master IFACE] [-type macvlan|vlan] [-ipv4 IP/PREFIX -gw4 GATEWAY] [-ipv6 IP/PREFIX -gw6 GATEWAY] [-dry-run] NODE_ID", cmdNodePublic},
+	},
+}
+
+var (
+	// cliNetwork is the network the cli commands talk to, selected with the
+	// global -network and -explorer flags or the environment
+	cliNetwork Network
+	// cliProfile overrides the active profile for a single command
+	cliProfile string
+	// cliClientOptions configure the explorer clients from the global
+	// -timeout, -retries, -proxy and -ca-cert flags
+	cliClientOptions []ClientOption
+)
+
+// runCLI executes the subcommand described by args and returns the process exit code
+func runCLI(args []string) int {
+	fs := newFlagSet("gofarmer")
+	fs.Usage = func() { cliUsage(os.Stderr) }
+	network := fs.String("network", os.Getenv(networkEnv), "network to use, one of "+strings.Join(explorersNames, ", "))
+	explorer := fs.String("explorer", os.Getenv(explorerEnv), "custom explorer url, overrides -network")
+	fs.StringVar(&cliProfile, "profile", os.Getenv(profileEnv), "profile to use instead of the active one")
+	store := fs.String("store", os.Getenv(secretStoreEnv), "where seeds are kept, one of file, secret-service or memory")
+	timeout := fs.Duration("timeout", DefaultTimeout, "how long a request to the explorer can take, 0 waits forever")
+	retries := fs.Int("retries", DefaultRetryPolicy.MaxAttempts-1, "how many times a failed request to the explorer is retried")
+	proxy := fs.String("proxy", os.Getenv(proxyEnv), "proxy url to reach the explorer through, direct to ignore HTTPS_PROXY")
+	caCert := fs.String("ca-cert", os.Getenv(caCertEnv), "PEM file of extra certificate authorities to trust, for private explorers")
+	if err := fs.Parse(args); err == flag.ErrHelp {
+		return exitOK
+	} else if err != nil {
+		return exitUsage
+	}
+	args = fs.Args()
+
+	if len(args) == 0 {
+		cliUsage(os.Stderr)
+		return exitUsage
+	}
+	if args[0] == "help" {
+		cliUsage(os.Stdout)
+		return exitOK
+	}
+
+	var err error
+	if cliNetwork, err = resolveNetwork(*network, *explorer); err != nil {
+		return cliError(exitUsage, "%s", err)
+	}
+	if seedStore, err = newSecretStore(*store); err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	if err := migrateLegacySeedFile(); err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	if cliClientOptions, err = cliConnectionOptions(*timeout, *retries, *proxy, *caCert); err != nil {
+		return cliError(exitUsage, "%s", err)
+	}
+
+	group, ok := cliCommands[args[0]]
+	if !ok || len(args) < 2 {
+		cliUsage(os.Stderr)
+		return exitUsage
+	}
+
+	cmd, ok := group[args[1]]
+	if !ok {
+		cliUsage(os.Stderr)
+		return exitUsage
+	}
+
+	return cmd.run(args[2:])
+}
+
+func cliUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: gofarmer [-network NETWORK | -explorer URL] [-profile NAME] [-store STORE] [<command> <subcommand> [flags]]")
+	fmt.Fprintln(w, "\nwithout any command the graphical interface is started")
+	fmt.Fprintf(w, "the network, profile and store can also be selected with the %s, %s, %s and %s environment variables\n", networkEnv, explorerEnv, profileEnv, secretStoreEnv)
+	fmt.Fprintf(w, "the passphrase of encrypted seed files is prompted for, or read from %s\n", passphraseEnv)
+	fmt.Fprintln(w, "use -profile NAME with 'identity register' to add a new profile\n\ncommands:")
+
+	groups := make([]string, 0, len(cliCommands))
+	for name := range cliCommands {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+
+	for _, group := range groups {
+		subs := make([]string, 0, len(cliCommands[group]))
+		for name := range cliCommands[group] {
+			subs = append(subs, name)
+		}
+		sort.Strings(subs)
+		for _, sub := range subs {
+			fmt.Fprintf(w, "  %s %s %s\n", group, sub, cliCommands[group][sub].usage)
+		}
+	}
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+func cliError(code int, format string, args ...interface{}) int {
+	fmt.Fprintf(os.Stderr, "error: "+format+"\n", args...)
+	return code
+}
+
+func printJSON(v interface{}) int {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return cliError(exitFailure, "failed to encode output: %s", err)
+	}
+	return exitOK
+}
+
+// readPassphrase returns the passphrase set in the environment or prompts
+// for it on the terminal, asking twice if confirm is set
+func readPassphrase(prompt string, confirm bool) (string, error) {
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return "", fmt.Errorf("no terminal to read the passphrase from, set %s", passphraseEnv)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: ", prompt)
+	passphrase, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if len(passphrase) == 0 {
+		return "", fmt.Errorf("passphrase can't be empty")
+	}
+
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+		again, err := terminal.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		if string(again) != string(passphrase) {
+			return "", fmt.Errorf("passphrases don't match")
+		}
+	}
+
+	return string(passphrase), nil
+}
+
+// loadIdentity loads the seed file at path, prompting for the passphrase if it's encrypted
+func loadIdentity(path string) (*UserIdentity, error) {
+	ui := &UserIdentity{}
+	err := ui.Load(path)
+	if err == ErrPassphraseRequired {
+		var passphrase string
+		if passphrase, err = readPassphrase(fmt.Sprintf("Passphrase of %s", path), false); err != nil {
+			return nil, err
+		}
+		err = ui.LoadWithPassphrase(path, passphrase)
+	}
+
+	return ui, err
+}
+
+// cliSeedPath returns the seed file of the profile selected for the command
+func cliSeedPath() (string, error) {
+	if cliProfile != "" {
+		return getProfileSeedPath(cliNetwork, cliProfile)
+	}
+	return getSeedPath(cliNetwork)
+}
+
+// cliSession holds the identity and the explorer client shared by the subcommands
+type cliSession struct {
+	seedPath string
+	identity *UserIdentity
+	client   *Client
+}
+
+// cliConnectionOptions returns the client options of the global flags,
+// retries are reported on stderr
+func cliConnectionOptions(timeout time.Duration, retries int, proxy, caCert string) ([]ClientOption, error) {
+	policy := DefaultRetryPolicy
+	policy.MaxAttempts = retries + 1
+	policy.OnRetry = func(s RetryStatus) {
+		if !s.Done {
+			fmt.Fprintln(os.Stderr, s)
+		}
+	}
+	opts, err := connectionOptions(proxy, caCert)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithTimeout(timeout), WithRetryPolicy(policy))
+	// fail on a bad CA file before running the command
+	if _, err := newClientConfig(opts...); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// newCLIClient returns a client of the cli network configured by the global flags
+func newCLIClient(id Identity) (*Client, error) {
+	return NewClient(cliNetwork.URL, id, cliClientOptions...)
+}
+
+// openSession loads the identity from the seed path if it exists and creates
+// an explorer client, signing requests with the identity when available
+func openSession() (*cliSession, error) {
+	seedPath, err := cliSeedPath()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &cliSession{seedPath: seedPath}
+	var id Identity
+	if seedExists(seedPath) {
+		ui, err := loadIdentity(seedPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load identity from %s: %w", seedPath, err)
+		}
+		s.identity = ui
+		id = ui
+	}
+
+	s.client, err = newCLIClient(id)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// requireUser returns the explorer record of the session identity, or the
+// exit code the command should fail with
+func (s *cliSession) requireUser() (User, int) {
+	if s.identity == nil {
+		return User{}, cliError(exitNoIdentity, "no identity found at %s, run 'gofarmer identity register' first", s.seedPath)
+	}
+	user, err := s.client.Phonebook.Get(s.identity.ThreebotID)
+	if err != nil {
+		return user, cliError(exitFailure, "failed to get user %d: %s", s.identity.ThreebotID, err)
+	}
+	return user, exitOK
+}
+
+func cmdIdentityRegister(args []string) int {
+	fs := newFlagSet("identity register")
+	name := fs.String("name", "", "3Bot name, should end with .3bot")
+	email := fs.String("email", "", "email address")
+	words := fs.String("words", "", "mnemonic words, leave empty to generate")
+	force := fs.Bool("force", false, "overwrite an existing identity")
+	encrypt := fs.Bool("encrypt", false, "encrypt the seed file with a passphrase")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if errs := validateIdentityData(*name, *email, *words); len(errs) != 0 {
+		return cliError(exitInvalid, "%s", strings.Join(errs, ", "))
+	}
+
+	seedPath, err := cliSeedPath()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	if seedExists(seedPath) && !*force {
+		return cliError(exitFailure, "identity already exists at %s, use -force to overwrite it", seedPath)
+	}
+
+	passphrase := ""
+	if *encrypt {
+		if passphrase, err = readPassphrase("New passphrase", true); err != nil {
+			return cliError(exitInvalid, "%s", err)
+		}
+	}
+
+	_, ui, err := generateID(cliNetwork.URL, *name, *email, seedPath, *words, passphrase, cliClientOptions...)
+	if err != nil {
+		return cliError(exitFailure, "failed to generate identity: %s", err)
+	}
+
+	fmt.Printf("3Bot ID: %d\n", ui.ThreebotID)
+	fmt.Printf("seed: %s\n", seedPath)
+	fmt.Fprintln(os.Stderr, "make sure the seed is backed up, e.g. with 'gofarmer identity export'")
+	return exitOK
+}
+
+func cmdIdentityShow(args []string) int {
+	fs := newFlagSet("identity show")
+	asJSON := fs.Bool("json", false, "print output as json")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	s, err := openSession()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	user, code := s.requireUser()
+	if code != exitOK {
+		return code
+	}
+
+	if *asJSON {
+		return printJSON(user)
+	}
+
+	fmt.Printf("3Bot ID: %d\n", user.ID)
+	fmt.Printf("Name: %s\n", user.Name)
+	fmt.Printf("Email: %s\n", user.Email)
+	fmt.Printf("Public key: %s\n", hex.EncodeToString(s.identity.Key().PublicKey))
+	fmt.Printf("Seed: %s\n", s.seedPath)
+	return exitOK
+}
+
+func cmdIdentityEncrypt(args []string) int {
+	fs := newFlagSet("identity encrypt")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	seedPath, err := cliSeedPath()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	if !seedExists(seedPath) {
+		return cliError(exitNoIdentity, "no identity found at %s, run 'gofarmer identity register' first", seedPath)
+	}
+
+	_, err = readEncryptedSeed(seedPath)
+	encrypted := err == nil
+
+	ui, err := loadIdentity(seedPath)
+	if err != nil {
+		return cliError(exitFailure, "failed to load identity from %s: %s", seedPath, err)
+	}
+
+	if encrypted {
+		// the environment holds the current passphrase, prompt for the new one
+		os.Unsetenv(passphraseEnv)
+	}
+	passphrase, err := readPassphrase("New passphrase", true)
+	if err != nil {
+		return cliError(exitInvalid, "%s", err)
+	}
+
+	if err := ui.SaveWithPassphrase(seedPath, passphrase); err != nil {
+		return cliError(exitFailure, "failed to save seed: %s", err)
+	}
+
+	fmt.Printf("seed %s is encrypted\n", seedPath)
+	return exitOK
+}
+
+func cmdIdentityConvert(args []string) int {
+	fs := newFlagSet("identity convert")
+	src := fs.String("seed", "", "path of the 1.0.0 seed file to convert")
+	id := fs.Int64("id", 0, "3Bot ID of the seed, looked up on the explorer by public key if not set")
+	encrypt := fs.Bool("encrypt", false, "encrypt the converted seed with a passphrase")
+	force := fs.Bool("force", false, "overwrite an existing identity")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *src == "" {
+		fmt.Fprintln(os.Stderr, "-seed is required")
+		return exitUsage
+	}
+
+	dst, err := cliSeedPath()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	if dst != *src && seedExists(dst) && !*force {
+		return cliError(exitFailure, "identity already exists at %s, use -force to overwrite it", dst)
+	}
+
+	passphrase := ""
+	if *encrypt {
+		if passphrase, err = readPassphrase("New passphrase", true); err != nil {
+			return cliError(exitInvalid, "%s", err)
+		}
+	}
+
+	expclient, err := newCLIClient(nil)
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+
+	ui, backup, err := convertLegacySeed(context.Background(), expclient, *src, dst, *id, passphrase)
+	if err != nil {
+		return cliError(exitFailure, "failed to convert seed: %s", err)
+	}
+
+	fmt.Printf("3Bot ID: %d\n", ui.ThreebotID)
+	fmt.Printf("seed: %s\n", dst)
+	fmt.Printf("backup: %s\n", backup)
+	return exitOK
+}
+
+func cmdIdentityRecover(args []string) int {
+	fs := newFlagSet("identity recover")
+	words := fs.String("words", "", "mnemonic words of the identity")
+	encrypt := fs.Bool("encrypt", false, "encrypt the seed file with a passphrase")
+	force := fs.Bool("force", false, "overwrite an existing identity")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *words == "" {
+		fmt.Fprintln(os.Stderr, "-words is required")
+		return exitUsage
+	}
+
+	seedPath, err := cliSeedPath()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	if seedExists(seedPath) && !*force {
+		return cliError(exitFailure, "identity already exists at %s, use -force to overwrite it", seedPath)
+	}
+
+	expclient, err := newCLIClient(nil)
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	ui, user, err := recoverIdentity(context.Background(), expclient, *words)
+	if err != nil {
+		return cliError(exitInvalid, "failed to recover identity: %s", err)
+	}
+
+	passphrase := ""
+	if *encrypt {
+		if passphrase, err = readPassphrase("New passphrase", true); err != nil {
+			return cliError(exitInvalid, "%s", err)
+		}
+	}
+	if err := ui.SaveWithPassphrase(seedPath, passphrase); err != nil {
+		return cliError(exitFailure, "failed to save seed: %s", err)
+	}
+
+	fmt.Printf("3Bot ID: %d\n", ui.ThreebotID)
+	fmt.Printf("Name: %s\n", user.Name)
+	fmt.Printf("seed: %s\n", seedPath)
+	return exitOK
+}
+
+func cmdIdentityExport(args []string) int {
+	fs := newFlagSet("identity export")
+	out := fs.String("out", "", "write the identity encrypted with a passphrase to this file")
+	paper := fs.String("paper", "", "write a printable paper backup page (html) to this file")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if (*out == "") == (*paper == "") {
+		fmt.Fprintln(os.Stderr, "one of -out or -paper is required")
+		return exitUsage
+	}
+
+	seedPath, err := cliSeedPath()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	if !seedExists(seedPath) {
+		return cliError(exitNoIdentity, "no identity found at %s, run 'gofarmer identity register' first", seedPath)
+	}
+	ui, err := loadIdentity(seedPath)
+	if err != nil {
+		return cliError(exitFailure, "failed to load identity from %s: %s", seedPath, err)
+	}
+
+	path, data := *paper, []byte(nil)
+	if *paper != "" {
+		data, err = paperBackup(ui, cliNetwork)
+	} else {
+		path = *out
+		var passphrase string
+		if passphrase, err = readPassphrase("Export passphrase", true); err != nil {
+			return cliError(exitInvalid, "%s", err)
+		}
+		data, err = exportIdentity(ui, cliNetwork, passphrase)
+	}
+	if err != nil {
+		return cliError(exitFailure, "failed to export identity: %s", err)
+	}
+
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return cliError(exitFailure, "failed to write %s: %s", path, err)
+	}
+
+	fmt.Printf("3Bot %d exported to %s\n", ui.ThreebotID, path)
+	return exitOK
+}
+
+func cmdIdentityImport(args []string) int {
+	fs := newFlagSet("identity import")
+	in := fs.String("in", "", "identity file written by 'identity export -out'")
+	encrypt := fs.Bool("encrypt", false, "encrypt the seed file with a passphrase")
+	force := fs.Bool("force", false, "overwrite an existing identity")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *in == "" {
+		fmt.Fprintln(os.Stderr, "-in is required")
+		return exitUsage
+	}
+
+	seedPath, err := cliSeedPath()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	if seedExists(seedPath) && !*force {
+		return cliError(exitFailure, "identity already exists at %s, use -force to overwrite it", seedPath)
+	}
+
+	data, err := ioutil.ReadFile(*in)
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	passphrase, err := readPassphrase(fmt.Sprintf("Passphrase of %s", *in), false)
+	if err != nil {
+		return cliError(exitInvalid, "%s", err)
+	}
+
+	ui, err := importIdentity(cliNetwork, data, passphrase, cliClientOptions...)
+	if err != nil {
+		return cliError(exitInvalid, "failed to import identity: %s", err)
+	}
+
+	seedPassphrase := ""
+	if *encrypt {
+		// the environment holds the passphrase of the identity file, prompt for the new one
+		os.Unsetenv(passphraseEnv)
+		if seedPassphrase, err = readPassphrase("New passphrase", true); err != nil {
+			return cliError(exitInvalid, "%s", err)
+		}
+	}
+	if err := ui.SaveWithPassphrase(seedPath, seedPassphrase); err != nil {
+		return cliError(exitFailure, "failed to save seed: %s", err)
+	}
+
+	fmt.Printf("3Bot ID: %d\n", ui.ThreebotID)
+	fmt.Printf("seed: %s\n", seedPath)
+	return exitOK
+}
+
+func cmdFarmCreate(args []string) int {
+	fs := newFlagSet("farm create")
+	name := fs.String("name", "", "farm name, alphanumeric")
+	address := fs.String("address", "", "TFT wallet address")
+	email := fs.String("email", "", "farm email, defaults to the identity email")
+	var wallets walletFlag
+	fs.Var(&wallets, "wallet", "extra ASSET:ADDRESS wallet, can be repeated")
+	locationFlags := addLocationFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	s, err := openSession()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	user, code := s.requireUser()
+	if code != exitOK {
+		return code
+	}
+
+	if *email == "" {
+		*email = user.Email
+	}
+	if errs := validateData(user.Name, *email, *name, *address); len(errs) != 0 {
+		return cliError(exitInvalid, "%s", strings.Join(errs, ", "))
+	}
+	location, _, err := locationFlags.apply(fs, Location{})
+	if err != nil {
+		return cliError(exitInvalid, "%s", err)
+	}
+
+	farm, err := registerFarm(s.client, *name, *email, *address, wallets, location, int(s.identity.ThreebotID))
+	if err != nil {
+		return cliError(exitFailure, "failed to register farm: %s", err)
+	}
+
+	fmt.Printf("farm with ID %d is created\n", farm.ID)
+	return exitOK
+}
+
+func cmdFarmUpdate(args []string) int {
+	fs := newFlagSet("farm update")
+	id := fs.Int64("id", 0, "farm ID")
+	name := fs.String("name", "", "new farm name")
+	address := fs.String("address", "", "new TFT wallet address")
+	email := fs.String("email", "", "new farm email")
+	var wallets walletFlag
+	fs.Var(&wallets, "wallet", "set the address of an asset as ASSET:ADDRESS, can be repeated")
+	removeWallets := fs.String("remove-wallet", "", "comma separated assets to remove the wallets of")
+	locationFlags := addLocationFlags(fs)
+	derive := fs.Bool("derive-location", false, "set the location to the one most of the farm nodes report, the location flags override it")
+	dryRun := fs.Bool("dry-run", false, "only print the changes")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *id <= 0 {
+		fmt.Fprintln(os.Stderr, "-id is required")
+		return exitUsage
+	}
+
+	s, err := openSession()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	user, code := s.requireUser()
+	if code != exitOK {
+		return code
+	}
+
+	farm, err := s.client.Directory.FarmGet(*id)
+	if err != nil {
+		return cliError(exitFailure, "failed to get farm %d: %s", *id, err)
+	}
+
+	if *name == "" {
+		*name = farm.Name
+	}
+	if *email == "" {
+		*email = farm.Email
+	}
+	if *address == "" {
+		*address = farmTFTAddress(farm)
+	}
+
+	if errs := validateData(user.Name, *email, *name, *address); len(errs) != 0 {
+		return cliError(exitInvalid, "%s", strings.Join(errs, ", "))
+	}
+
+	location := farm.Location
+	if *derive {
+		location, err = deriveFarmLocation(s.client, farm)
+		if err != nil {
+			return cliError(exitFailure, "%s", err)
+		}
+	}
+	location, locationSet, err := locationFlags.apply(fs, location)
+	if err != nil {
+		return cliError(exitInvalid, "%s", err)
+	}
+
+	_, code = submitFarmUpdate(s, farm, func(f *Farm) {
+		setFarmDetails(f, *name, *email, *address)
+		if *derive || locationSet {
+			f.Location = location
+		}
+		for _, w := range wallets {
+			f.WalletAddresses = setWalletAddress(f.WalletAddresses, w)
+		}
+		for _, asset := range strings.Split(*removeWallets, ",") {
+			if asset = strings.TrimSpace(asset); asset != "" {
+				f.WalletAddresses = removeWalletAddress(f.WalletAddresses, asset)
+			}
+		}
+	}, *dryRun)
+	return code
+}
+
+func cmdFarmTransfer(args []string) int {
+	fs := newFlagSet("farm transfer")
+	id := fs.Int64("id", 0, "farm ID")
+	to := fs.Int64("to", 0, "3Bot ID of the new owner")
+	confirm := fs.String("confirm", "", "farm name, to confirm the transfer without being prompted")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *id <= 0 || *to <= 0 {
+		fmt.Fprintln(os.Stderr, "-id and -to are required")
+		return exitUsage
+	}
+
+	s, err := openSession()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	if _, code := s.requireUser(); code != exitOK {
+		return code
+	}
+
+	farm, err := s.client.Directory.FarmGet(*id)
+	if err != nil {
+		return cliError(exitFailure, "failed to get farm %d: %s", *id, err)
+	}
+	transfer, err := newFarmTransfer(s.client, cliNetwork, s.identity, farm, *to)
+	if err != nil {
+		return cliError(exitInvalid, "%s", err)
+	}
+
+	fmt.Printf("farm %s (%d) will be owned by %s (%s, 3Bot %d), you won't be able to manage it anymore\n", farm.Name, farm.ID, transfer.ToName, transfer.ToEmail, transfer.To)
+	if *confirm == "" {
+		fmt.Fprint(os.Stderr, "type the farm name to confirm: ")
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		*confirm = line
+	}
+	if err := checkTransferConfirmation(transfer, *confirm); err != nil {
+		if logErr := transfer.Decline(cliNetwork, err.Error()); logErr != nil {
+			fmt.Fprintln(os.Stderr, logErr)
+		}
+		return cliError(exitInvalid, "%s", err)
+	}
+
+	if _, err := transfer.Submit(s.client, cliNetwork, farm); err != nil {
+		return cliError(exitFailure, "failed to transfer farm: %s", err)
+	}
+	fmt.Printf("farm with ID %d is transferred to %d\n", farm.ID, transfer.To)
+	return exitOK
+}
+
+func cmdFarmTransfers(args []string) int {
+	fs := newFlagSet("farm transfers")
+	asJSON := fs.Bool("json", false, "print the transfers as JSON")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	transfers, err := readTransferLog(cliNetwork)
+	if err != nil {
+		return cliError(exitFailure, "failed to read the transfer log: %s", err)
+	}
+	if *asJSON {
+		return printJSON(transfers)
+	}
+	for _, t := range transfers {
+		fmt.Println(t)
+	}
+	return exitOK
+}
+
+func cmdFarmCapacity(args []string) int {
+	fs := newFlagSet("farm capacity")
+	id := fs.Int64("id", 0, "farm ID")
+	asJSON := fs.Bool("json", false, "print output as json")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *id <= 0 {
+		fmt.Fprintln(os.Stderr, "-id is required")
+		return exitUsage
+	}
+
+	s, err := openSession()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	nodes, _, err := ListAllNodesAndNames(s.client, *id)
+	if err != nil {
+		return cliError(exitFailure, "failed to list nodes: %s", err)
+	}
+
+	usage, workloads := farmUsage(nodes), workloadBreakdown(nodes)
+	if *asJSON {
+		return printJSON(struct {
+			Nodes     int             `json:"nodes"`
+			Resources []resourceUsage `json:"resources"`
+			Workloads []workloadCount `json:"workloads"`
+		}{len(nodes), usage, workloads})
+	}
+
+	fmt.Printf("%d nodes\n", len(nodes))
+	for _, u := range usage {
+		fmt.Println(u)
+	}
+	fmt.Printf("\nWorkloads:\n%s\n", formatWorkloads(workloads))
+	return exitOK
+}
+
+// deriveFarmLocation returns the location most of the nodes of farm report
+func deriveFarmLocation(client *Client, farm Farm) (Location, error) {
+	nodes, _, err := ListAllNodesAndNames(client, farm.ID)
+	if err != nil {
+		return farm.Location, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	location, count, err := deriveLocation(nodes)
+	if err != nil {
+		return farm.Location, err
+	}
+	fmt.Printf("%d of the %d nodes are in %s\n", count, len(nodes), location)
+	return location, nil
+}
+
+// locationFlags are the flags editing a farm location
+type locationFlags struct {
+	city      *string
+	country   *string
+	latitude  *float64
+	longitude *float64
+}
+
+func addLocationFlags(fs *flag.FlagSet) *locationFlags {
+	return &locationFlags{
+		city:      fs.String("city", "", "farm city"),
+		country:   fs.String("country", "", "farm country name or ISO code, the continent is deduced from it"),
+		latitude:  fs.Float64("lat", 0, "farm latitude"),
+		longitude: fs.Float64("long", 0, "farm longitude"),
+	}
+}
+
+// apply sets the location flags given on the command line on l and
+// validates it, l is returned as is if none was given
+func (f *locationFlags) apply(fs *flag.FlagSet, l Location) (Location, bool, error) {
+	set := false
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "city":
+			l.City = *f.city
+		case "country":
+			l.Country, l.Continent = *f.country, ""
+		case "lat":
+			l.Latitude = *f.latitude
+		case "long":
+			l.Longitude = *f.longitude
+		default:
+			return
+		}
+		set = true
+	})
+	if !set {
+		return l, false, nil
+	}
+	l, err := normalizeLocation(l)
+	return l, set, err
+}
+
+// submitFarmUpdate prints the changes edit makes to farm and submits them
+// unless dryRun is set
+func submitFarmUpdate(s *cliSession, farm Farm, edit func(farm *Farm), dryRun bool) (Farm, int) {
+	update, _, err := newFarmUpdate(s.client, farm, edit)
+	if err != nil {
+		return farm, cliError(exitFailure, "%s", err)
+	}
+	if len(update.Changes) == 0 {
+		fmt.Printf("farm with ID %d is unchanged\n", farm.ID)
+		return farm, exitOK
+	}
+
+	fmt.Println(formatFarmChanges(update.Changes))
+	if dryRun {
+		return farm, exitOK
+	}
+
+	updated, err := update.Submit(s.client)
+	if err != nil {
+		return updated, cliError(exitFailure, "failed to update farm: %s", err)
+	}
+
+	fmt.Printf("farm with ID %d is updated\n", updated.ID)
+	return updated, exitOK
+}
+
+func cmdFarmPricing(args []string) int {
+	fs := newFlagSet("farm pricing")
+	id := fs.Int64("id", 0, "farm ID")
+	custom := fs.Bool("custom", false, "enable custom pricing")
+	grid3 := fs.Bool("grid3", false, "grid3 compliant pricing")
+	currency := fs.String("currency", "", "currency of the prices, one of "+strings.Join(priceCurrencyNames(), ", "))
+	prices := map[string]*float64{}
+	for _, name := range []string{"cu", "su", "nu", "ipv4u", "cru", "mru", "hru", "sru", "nru"} {
+		prices[name] = fs.Float64(name, 0, fmt.Sprintf("%s price", strings.ToUpper(name)))
+	}
+	asJSON := fs.Bool("json", false, "print output as json")
+	dryRun := fs.Bool("dry-run", false, "only print the changes and the earnings with the new pricing")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *id <= 0 {
+		fmt.Fprintln(os.Stderr, "-id is required")
+		return exitUsage
+	}
+
+	s, err := openSession()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	farm, err := s.client.Directory.FarmGet(*id)
+	if err != nil {
+		return cliError(exitFailure, "failed to get farm %d: %s", *id, err)
+	}
+
+	// only the flags given on the command line are changed
+	pricing := pricingOf(farm)
+	edited := false
+	var visitErr error
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "id", "json", "dry-run":
+			return
+		case "custom":
+			pricing.EnableCustomPricing = *custom
+		case "grid3":
+			pricing.IsGrid3Compliant = *grid3
+		case "currency":
+			c, err := parsePriceCurrency(*currency)
+			if err != nil {
+				visitErr = err
+				return
+			}
+			pricing.CloudUnits.Currency = c
+			pricing.Resources = resourcePrice(farm, c)
+		}
+		edited = true
+	})
+	if visitErr != nil {
+		return cliError(exitInvalid, "%s", visitErr)
+	}
+	// prices are applied after the currency so they are set in the new one
+	fs.Visit(func(f *flag.Flag) {
+		targets := map[string]*float64{
+			"cu": &pricing.CloudUnits.CU, "su": &pricing.CloudUnits.SU, "nu": &pricing.CloudUnits.NU, "ipv4u": &pricing.CloudUnits.IPv4U,
+			"cru": &pricing.Resources.Cru, "mru": &pricing.Resources.Mru, "hru": &pricing.Resources.Hru, "sru": &pricing.Resources.Sru, "nru": &pricing.Resources.Nru,
+		}
+		if target, ok := targets[f.Name]; ok {
+			*target = *prices[f.Name]
+		}
+	})
+
+	if edited {
+		var code int
+		if _, code = s.requireUser(); code != exitOK {
+			return code
+		}
+		if err := pricing.Validate(); err != nil {
+			return cliError(exitInvalid, "%s", err)
+		}
+		if farm, code = submitFarmUpdate(s, farm, pricing.apply, *dryRun); code != exitOK {
+			return code
+		}
+		if !*dryRun {
+			pricing = pricingOf(farm)
+		}
+	}
+
+	nodes, _, err := ListAllNodesAndNames(s.client, farm.ID)
+	if err != nil {
+		return cliError(exitFailure, "failed to list nodes: %s", err)
+	}
+	earnings := make([]nodeEarnings, 0, len(nodes))
+	for _, node := range nodes {
+		earnings = append(earnings, estimateEarnings(node, pricing))
+	}
+
+	if *asJSON {
+		return printJSON(struct {
+			Pricing  farmPricing
+			Earnings []nodeEarnings
+		}{pricing, earnings})
+	}
+
+	c := pricing.CloudUnits
+	fmt.Printf("Custom pricing: %t\n", pricing.EnableCustomPricing)
+	fmt.Printf("Grid3 compliant: %t\n", pricing.IsGrid3Compliant)
+	fmt.Printf("Currency: %s\n", c.Currency)
+	fmt.Printf("Cloud units: CU %s, SU %s, NU %s, IPv4U %s\n", formatPrice(c.CU), formatPrice(c.SU), formatPrice(c.NU), formatPrice(c.IPv4U))
+	r := pricing.Resources
+	fmt.Printf("Resources: CRU %s, MRU %s, HRU %s, SRU %s, NRU %s\n", formatPrice(r.Cru), formatPrice(r.Mru), formatPrice(r.Hru), formatPrice(r.Sru), formatPrice(r.Nru))
+	fmt.Println("Monthly earnings, network and public IPs not included:")
+	for _, e := range earnings {
+		fmt.Printf("  %s\n", e)
+	}
+	return exitOK
+}
+
+func cmdFarmList(args []string) int {
+	fs := newFlagSet("farm list")
+	owner := fs.Int64("owner", 0, "3Bot ID of the farms owner, defaults to the identity")
+	asJSON := fs.Bool("json", false, "print output as json")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	s, err := openSession()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	if *owner == 0 {
+		if s.identity == nil {
+			return cliError(exitNoIdentity, "no identity found at %s, use -owner or register an identity", s.seedPath)
+		}
+		*owner = s.identity.ThreebotID
+	}
+
+	farms, _, err := ListAllFarmsAndNames(s.client, *owner)
+	if err != nil {
+		return cliError(exitFailure, "failed to list farms: %s", err)
+	}
+
+	if *asJSON {
+		return printJSON(farms)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tOWNER\tEMAIL")
+	for _, f := range farms {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\n", f.ID, f.Name, f.ThreebotID, f.Email)
+	}
+	w.Flush()
+	return exitOK
+}
+
+func cmdIPList(args []string) int {
+	fs := newFlagSet("ip list")
+	farm := fs.Int64("farm", 0, "farm ID")
+	asJSON := fs.Bool("json", false, "print output as json")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *farm <= 0 {
+		fmt.Fprintln(os.Stderr, "-farm is required")
+		return exitUsage
+	}
+
+	s, err := openSession()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	f, err := s.client.Directory.FarmGet(*farm)
+	if err != nil {
+		return cliError(exitFailure, "failed to get farm %d: %s", *farm, err)
+	}
+
+	return printFarmIPs(f, *asJSON)
+}
+
+func cmdIPAdd(args []string) int {
+	fs := newFlagSet("ip add")
+	farm := fs.Int64("farm", 0, "farm ID")
+	address := fs.String("address", "", "ip in CIDR notation, first ip of the range if -last is set")
+	gateway := fs.String("gateway", "", "gateway of the ip")
+	last := fs.String("last", "", "last ip of a range to add")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *farm <= 0 || *address == "" || *gateway == "" {
+		fmt.Fprintln(os.Stderr, "-farm, -address and -gateway are required")
+		return exitUsage
+	}
+
+	addresses := []string{*address}
+	if *last != "" {
+		var err error
+		if addresses, err = expandIPRange(*address, *last); err != nil {
+			return cliError(exitInvalid, "%s", err)
+		}
+	}
+	ips := make([]PublicIP, 0, len(addresses))
+	for _, a := range addresses {
+		if err := validatePublicIP(a, *gateway); err != nil {
+			return cliError(exitInvalid, "%s", err)
+		}
+		ips = append(ips, PublicIP{Address: a, Gateway: *gateway})
+	}
+
+	s, err := openSession()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	if _, code := s.requireUser(); code != exitOK {
+		return code
+	}
+
+	f, err := addFarmIPs(s.client, *farm, ips)
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	return printFarmIPs(f, false)
+}
+
+func cmdIPRemove(args []string) int {
+	fs := newFlagSet("ip remove")
+	farm := fs.Int64("farm", 0, "farm ID")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *farm <= 0 || fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "-farm and an ip are required")
+		return exitUsage
+	}
+
+	s, err := openSession()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	if _, code := s.requireUser(); code != exitOK {
+		return code
+	}
+
+	f, err := deleteFarmIP(s.client, *farm, fs.Arg(0))
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	return printFarmIPs(f, false)
+}
+
+func printFarmIPs(f Farm, asJSON bool) int {
+	if asJSON {
+		return printJSON(f.IPAddresses)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDRESS\tGATEWAY\tRESERVATION")
+	for _, ip := range f.IPAddresses {
+		reservation := "-"
+		if ip.Reserved() {
+			reservation = fmt.Sprint(ip.ReservationID)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", ip.Address, ip.Gateway, reservation)
+	}
+	w.Flush()
+	return exitOK
+}
+
+func cmdNodeList(args []string) int {
+	fs := newFlagSet("node list")
+	farm := fs.Int64("farm", 0, "farm ID")
+	asJSON := fs.Bool("json", false, "print output as json")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *farm <= 0 {
+		fmt.Fprintln(os.Stderr, "-farm is required")
+		return exitUsage
+	}
+
+	s, err := openSession()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+
+	nodes, _, err := ListAllNodesAndNames(s.client, *farm)
+	if err != nil {
+		return cliError(exitFailure, "failed to list nodes: %s", err)
+	}
+
+	if *asJSON {
+		return printJSON(nodes)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE ID\tHOSTNAME\tVERSION\tUPTIME")
+	for _, n := range nodes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", n.NodeId, n.HostName, n.OsVersion, humanize.Time(time.Unix(n.Uptime, 0)))
+	}
+	w.Flush()
+	return exitOK
+}
+
+func cmdNodeShow(args []string) int {
+	fs := newFlagSet("node show")
+	farm := fs.Int64("farm", 0, "farm ID the node belongs to")
+	proofs := fs.Bool("proofs", false, "also get the hardware and disk proofs of the node")
+	asJSON := fs.Bool("json", false, "print output as json")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *farm <= 0 || fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "-farm and a node ID are required")
+		return exitUsage
+	}
+	nodeID := fs.Arg(0)
+
+	s, err := openSession()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+
+	nodes, _, err := ListAllNodesAndNames(s.client, *farm)
+	if err != nil {
+		return cliError(exitFailure, "failed to list nodes: %s", err)
+	}
+
+	for _, n := range nodes {
+		if n.NodeId != nodeID {
+			continue
+		}
+		if *proofs {
+			withProofs, err := getNodeProofs(s.client, n.NodeId)
+			if err != nil {
+				return cliError(exitFailure, "%s", err)
+			}
+			n.Proofs = withProofs.Proofs
+		}
+		if *asJSON {
+			return printJSON(n)
+		}
+
+		fmt.Printf("Node ID: %s\n", n.NodeId)
+		fmt.Printf("Node Version: %s\n", n.OsVersion)
+		fmt.Printf("Hostname: %s\n", n.HostName)
+		fmt.Printf("Farm ID: %d\n", n.FarmId)
+		fmt.Printf("Location: %s - %s\n", n.Location.Country, n.Location.City)
+		fmt.Printf("Uptime: %s\n", humanize.Time(time.Unix(n.Uptime, 0)))
+		for _, u := range nodeUsage(n) {
+			fmt.Println(u)
+		}
+		fmt.Printf("\nWorkloads:\n%s\n", formatWorkloads(workloadBreakdown([]Node{n})))
+		fmt.Printf("\nStatus:\n%s\n", formatNodeStatus(n))
+		fmt.Printf("\nInterfaces:\n%s\n", formatIfaces(n.Ifaces))
+		fmt.Printf("\nPublic config:\n%s\n", formatPublicConfig(n.PublicConfig))
+		if *proofs {
+			fmt.Printf("\nProofs:\n%s\n", formatProofs(n.Proofs))
+		}
+		return exitOK
+	}
+
+	return cliError(exitFailure, "node %s not found in farm %d", nodeID, *farm)
+}
+
+func cmdNodePublic(args []string) int {
+	fs := newFlagSet("node public")
+	master := fs.String("master", "", "interface of the node the public interface is created on")
+	ifaceType := fs.String("type", "", "interface type, macvlan or vlan")
+	ipv4 := fs.String("ipv4", "", "public IPv4 in CIDR notation")
+	gw4 := fs.String("gw4", "", "IPv4 gateway")
+	ipv6 := fs.String("ipv6", "", "public IPv6 in CIDR notation")
+	gw6 := fs.String("gw6", "", "IPv6 gateway")
+	dryRun := fs.Bool("dry-run", false, "only print the new config")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "a node ID is required")
+		return exitUsage
+	}
+
+	s, err := openSession()
+	if err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	node, err := s.client.Directory.NodeGet(fs.Arg(0), false)
+	if err != nil {
+		return cliError(exitFailure, "failed to get node %s: %s", fs.Arg(0), err)
+	}
+
+	fmt.Printf("Current public config:\n%s\n", formatPublicConfig(node.PublicConfig))
+	if fs.NFlag() == 0 || (fs.NFlag() == 1 && *dryRun) {
+		return exitOK
+	}
+	if _, code := s.requireUser(); code != exitOK {
+		return code
+	}
+
+	// the config is edited on top of the current one
+	current := PublicIface{Type: IfaceTypeMacvlan}
+	if node.PublicConfig != nil {
+		current = *node.PublicConfig
+	}
+	values := map[string]*string{"master": master, "type": ifaceType, "ipv4": ipv4, "gw4": gw4, "ipv6": ipv6, "gw6": gw6}
+	defaults := map[string]string{
+		"master": current.Master, "type": current.Type.String(),
+		"ipv4": current.Ipv4, "gw4": joinIPs([]net.IP{current.Gw4}),
+		"ipv6": current.Ipv6, "gw6": joinIPs([]net.IP{current.Gw6}),
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for name, value := range values {
+		if !set[name] {
+			*value = defaults[name]
+		}
+	}
+
+	pub, err := parsePublicConfig(*master, *ifaceType, *ipv4, *gw4, *ipv6, *gw6)
+	if err == nil {
+		err = validatePublicConfig(pub, node.Ifaces)
+	}
+	if err != nil {
+		return cliError(exitInvalid, "%s", err)
+	}
+	fmt.Printf("\nNew public config:\n%s\n", formatPublicConfig(&pub))
+	if *dryRun {
+		return exitOK
+	}
+
+	if _, err := setNodePublicConfig(s.client, node, pub); err != nil {
+		return cliError(exitFailure, "%s", err)
+	}
+	fmt.Printf("node %s is configured on %s\n", node.NodeId, pub.Master)
+	return exitOK
+}
+
+func cmdProfileList(args []string) int {
+	fs := newFlagSet("profile list")
+	asJSON := fs.Bool("json", false, "print output as json")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	profiles, err := listProfiles(cliNetwork)
+	if err != nil {
+		return cliError(exitFailure, "failed to list profiles: %s", err)
+	}
+
+	if *asJSON {
+		return printJSON(profiles)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTIVE\tNAME\t3BOT ID\tNETWORK\tPUBLIC KEY")
+	for _, p := range profiles {
+		active := ""
+		if p.Active {
+			active = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", active, p.Name, p.ThreebotID, p.Network, p.PublicKey)
+	}
+	w.Flush()
+	return exitOK
+}
+
+func cmdProfileUse(args []string) int {
+	fs := newFlagSet("profile use")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "a profile name is required")
+		return exitUsage
+	}
+	name := fs.Arg(0)
+
+	path, err := getProfileSeedPath(cliNetwork, name)
+	if err != nil {
+		return cliError(exitInvalid, "%s", err)
+	}
+	if !seedExists(path) {
+		return cliError(exitNoIdentity, "profile %s doesn't exist, register it with 'gofarmer -profile %s identity register'", name, name)
+	}
+
+	if err := setActiveProfile(cliNetwork, name); err != nil {
+		return cliError(exitFailure, "failed to activate profile: %s", err)
+	}
+
+	fmt.Printf("profile %s is active on %s\n", name, cliNetwork.Name)
+	return exitOK
+}
+
+func cmdProfileImport(args []string) int {
+	fs := newFlagSet("profile import")
+	name := fs.String("name", "", "profile name")
+	words := fs.String("words", "", "mnemonic words of the identity")
+	id := fs.Int64("id", 0, "3Bot ID of the identity")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *name == "" || *words == "" || *id <= 0 {
+		fmt.Fprintln(os.Stderr, "-name, -words and -id are required")
+		return exitUsage
+	}
+
+	ui, err := importProfile(cliNetwork, *name, *words, *id, cliClientOptions...)
+	if err != nil {
+		return cliError(exitFailure, "failed to import profile: %s", err)
+	}
+
+	fmt.Printf("profile %s imported with 3Bot ID %d\n", *name, ui.ThreebotID)
+	return exitOK
+}
+
+func cmdProfileRemove(args []string) int {
+	fs := newFlagSet("profile remove")
+	force := fs.Bool("force", false, "remove the profile without confirmation, even if it's the active one")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "a profile name is required")
+		return exitUsage
+	}
+	name := fs.Arg(0)
+
+	// the seed is the only copy of the identity key unless it was exported
+	if !*force {
+		active, err := getActiveProfile(cliNetwork)
+		if err != nil {
+			return cliError(exitFailure, "%s", err)
+		}
+		if active == name {
+			return cliError(exitInvalid, "profile %s is the active one, switch to another profile or use -force", name)
+		}
+		if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+			return cliError(exitInvalid, "no terminal to confirm the removal of profile %s, use -force", name)
+		}
+		fmt.Fprintf(os.Stderr, "the seed of profile %s will be deleted, type the profile name to confirm: ", name)
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.TrimSpace(line) != name {
+			return cliError(exitInvalid, "profile name doesn't match, profile %s is kept", name)
+		}
+	}
+
+	if err := removeProfile(cliNetwork, name); err != nil {
+		return cliError(exitFailure, "failed to remove profile: %s", err)
+	}
+
+	fmt.Printf("profile %s removed\n", name)
+	return exitOK
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/ed25519"
 	"net"
 
@@ -13,8 +14,10 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
 
 	"github.com/zaibon/httpsig"
 )
@@ -25,6 +28,10 @@ var (
 	// this error is resolved by retrying again later.
 	ErrRequestFailure = fmt.Errorf("request failure")
 
+	// DefaultTimeout is the time NewClient gives a request to the explorer
+	// to complete, including reading the response
+	DefaultTimeout = 30 * time.Second
+
 	successCodes = []int{
 		http.StatusOK,
 		http.StatusCreated,
@@ -227,10 +234,12 @@ func NewUser() (User, error) {
 }
 
 type httpClient struct {
-	u        *url.URL
-	cl       http.Client
-	signer   *httpsig.Signer
-	identity string
+	u         *url.URL
+	cl        http.Client
+	signer    *httpsig.Signer
+	identity  string
+	retry     RetryPolicy
+	userAgent string
 }
 
 // HTTPError is the error type returned by the client
@@ -249,22 +258,34 @@ func (h HTTPError) Response() http.Response {
 	return *h.resp
 }
 
-func newHTTPClient(raw string, id Identity) (*httpClient, error) {
+func newHTTPClient(raw string, id Identity, opts ...ClientOption) (*httpClient, error) {
 	u, err := url.Parse(raw)
 	if err != nil {
 		return nil, fmt.Errorf("invalid url: %w", err)
 	}
 
+	cfg, err := newClientConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := cfg.roundTripper()
+	if err != nil {
+		return nil, err
+	}
+
 	if !strings.HasSuffix(u.Path, "/api/v1") {
 		u.Path = "/api/v1"
 	}
 
 	client := &httpClient{
-		u: u,
+		u:         u,
+		cl:        http.Client{Timeout: cfg.timeout, Transport: transport},
+		retry:     cfg.retry,
+		userAgent: cfg.userAgent,
 	}
 
 	if id != nil {
-		client.signer = httpsig.NewSigner(id.Identity(), id.PrivateKey(), httpsig.Ed25519, []string{"(created)", "date", "threebot-id"})
+		client.signer = httpsig.NewSigner(id.Identity(), id.PrivateKey(), httpsig.Ed25519, signatureHeaders)
 		client.identity = id.Identity()
 	}
 
@@ -276,7 +297,7 @@ func (c *httpClient) url(p ...string) string {
 	allParts := []string{b.Path}
 	allParts = append(allParts, p...)
 	b.Path = strings.Join(allParts, "/")
-	fmt.Println(b)
+	log.Debug().Str("url", b.String()).Msg("explorer request")
 	return b.String()
 
 }
@@ -290,6 +311,66 @@ func (c *httpClient) sign(r *http.Request) error {
 	return c.signer.Sign(r)
 }
 
+// do signs and sends req, retrying it as the retry policy of the client
+// allows. The error is caused by the context error if the context of req is
+// done and by ErrRequestFailure otherwise
+func (c *httpClient) do(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to rewind request body")
+			}
+			req.Body = body
+		}
+		if c.userAgent != "" {
+			req.Header.Set("User-Agent", c.userAgent)
+		}
+		// the signature covers the date, sign every attempt again
+		req.Header.Del("date")
+		if err := c.sign(req); err != nil {
+			return nil, errors.Wrap(err, "failed to sign HTTP request")
+		}
+
+		response, err := c.cl.Do(req)
+		if ctxErr := req.Context().Err(); err != nil && ctxErr != nil {
+			return nil, errors.Wrapf(ctxErr, "request to %s", req.URL.Path)
+		}
+
+		status := RetryStatus{Method: req.Method, Path: req.URL.Path, Attempt: attempt, MaxAttempts: c.retry.MaxAttempts, Err: err}
+		delay, retry := c.retry.next(req, response, err, attempt)
+		if !retry {
+			if err != nil {
+				err = errors.Wrapf(ErrRequestFailure, "reason: %s", err)
+			}
+			if attempt > 1 {
+				status.Done, status.Err = true, err
+				if err == nil && response.StatusCode >= 400 {
+					status.Err = fmt.Errorf("%s", response.Status)
+				}
+				c.retry.notify(status)
+			}
+			return response, err
+		}
+
+		if response != nil {
+			status.Err = fmt.Errorf("%s", response.Status)
+			ioutil.ReadAll(response.Body)
+			response.Body.Close()
+		}
+		status.Delay = delay
+		c.retry.notify(status)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, errors.Wrapf(req.Context().Err(), "request to %s", req.URL.Path)
+		}
+	}
+}
+
 func (c *httpClient) process(response *http.Response, output interface{}, expect ...int) error {
 	defer response.Body.Close()
 
@@ -341,109 +422,91 @@ func (c *httpClient) process(response *http.Response, output interface{}, expect
 	return nil
 }
 
-func (c *httpClient) post(u string, input interface{}, output interface{}, expect ...int) (*http.Response, error) {
+func (c *httpClient) post(ctx context.Context, u string, input interface{}, output interface{}, expect ...int) (*http.Response, error) {
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(input); err != nil {
 		return nil, errors.Wrap(err, "failed to serialize request body")
 	}
 
-	req, err := http.NewRequest(http.MethodPost, u, &buf)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, &buf)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create new HTTP request")
 	}
 
-	if err := c.sign(req); err != nil {
-		return nil, errors.Wrap(err, "failed to sign HTTP request")
-	}
-	response, err := c.cl.Do(req)
+	response, err := c.do(req)
 	if err != nil {
-		return nil, errors.Wrapf(ErrRequestFailure, "reason: %s", err)
+		return nil, err
 	}
 
 	return response, c.process(response, output, expect...)
 }
 
-func (c *httpClient) put(u string, input interface{}, output interface{}, expect ...int) (*http.Response, error) {
+func (c *httpClient) put(ctx context.Context, u string, input interface{}, output interface{}, expect ...int) (*http.Response, error) {
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(input); err != nil {
 		return nil, errors.Wrap(err, "failed to serialize request body")
 	}
-	req, err := http.NewRequest(http.MethodPut, u, &buf)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, &buf)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to build request")
 	}
 
-	if err := c.sign(req); err != nil {
-		return nil, errors.Wrap(err, "failed to sign HTTP request")
-	}
-
-	response, err := c.cl.Do(req)
+	response, err := c.do(req)
 	if err != nil {
-		return nil, errors.Wrapf(ErrRequestFailure, "reason: %s", err)
+		return nil, err
 	}
 
 	return nil, c.process(response, output, expect...)
 }
 
-func (c *httpClient) get(u string, query url.Values, output interface{}, expect ...int) (*http.Response, error) {
+func (c *httpClient) get(ctx context.Context, u string, query url.Values, output interface{}, expect ...int) (*http.Response, error) {
 	if len(query) > 0 {
 		u = fmt.Sprintf("%s?%s", u, query.Encode())
 	}
 
-	req, err := http.NewRequest(http.MethodGet, u, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create new HTTP request")
 	}
 
-	if err := c.sign(req); err != nil {
-		return nil, errors.Wrap(err, "failed to sign HTTP request")
-	}
-
-	response, err := c.cl.Do(req)
+	response, err := c.do(req)
 	if err != nil {
-		return nil, errors.Wrapf(ErrRequestFailure, "reason: %s", err)
+		return nil, err
 	}
 
 	return response, c.process(response, output, expect...)
 }
 
-func (c *httpClient) delete(u string, query url.Values, output interface{}, expect ...int) (*http.Response, error) {
+func (c *httpClient) delete(ctx context.Context, u string, query url.Values, output interface{}, expect ...int) (*http.Response, error) {
 	if len(query) > 0 {
 		u = fmt.Sprintf("%s?%s", u, query.Encode())
 	}
-	req, err := http.NewRequest(http.MethodDelete, u, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u, nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to build request")
 	}
 
-	if err := c.sign(req); err != nil {
-		return nil, errors.Wrap(err, "failed to sign HTTP request")
-	}
-
-	response, err := c.cl.Do(req)
+	response, err := c.do(req)
 	if err != nil {
-		return nil, errors.Wrapf(ErrRequestFailure, "reason: %s", err)
+		return nil, err
 	}
 
 	return response, c.process(response, output, expect...)
 }
-func (c *httpClient) deleteWithBody(u string, input interface{}, output interface{}, expect ...int) (*http.Response, error) {
+func (c *httpClient) deleteWithBody(ctx context.Context, u string, input interface{}, output interface{}, expect ...int) (*http.Response, error) {
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(input); err != nil {
 		return nil, errors.Wrap(err, "failed to serialize request body")
 	}
 
-	req, err := http.NewRequest(http.MethodDelete, u, &buf)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u, &buf)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create new HTTP request")
 	}
 
-	if err := c.sign(req); err != nil {
-		return nil, errors.Wrap(err, "failed to sign HTTP request")
-	}
-	response, err := c.cl.Do(req)
+	response, err := c.do(req)
 	if err != nil {
-		return nil, errors.Wrapf(ErrRequestFailure, "reason: %s", err)
+		return nil, err
 	}
 
 	return response, c.process(response, output, expect...)
@@ -454,6 +517,8 @@ type (
 	Client struct {
 		Phonebook Phonebook
 		Directory Directory
+
+		http *httpClient
 	}
 
 	// NodeIter iterator over all nodes
@@ -471,25 +536,65 @@ type (
 	}
 
 	// Directory API interface
+	//
+	// The Context variants of the methods stop waiting for the explorer when
+	// ctx is done, the others use context.Background()
 	Directory interface {
 		FarmRegister(farm Farm) (int64, error)
 		FarmUpdate(farm Farm) error
 		FarmList(tid int64, name string, page *Pager) (farms []Farm, err error)
 		FarmGet(id int64) (farm Farm, err error)
 		Farms(cacheSize int) FarmIter
+		FarmAddIP(id int64, ip PublicIP) error
+		FarmDeleteIP(id int64, ipaddr string) error
 
+		NodeRegister(node Node) error
+		NodeList(filter NodeFilter, pager *Pager) (nodes []Node, err error)
+		NodeGet(id string, proofs bool) (node Node, err error)
+		Nodes(cacheSize int, proofs bool) NodeIter
+		NodeSetInterfaces(id string, ifaces []Iface) error
+		NodeSetPorts(id string, ports []uint) error
+		NodeSetPublic(id string, pub PublicIface) error
 		NodeUpdateUptime(id string, uptime uint64) error
 		NodeUpdateUsedResources(id string, resources ResourceAmount, workloads WorkloadAmount) error
-		NodeList(filter NodeFilter, pager *Pager) (nodes []Node, err error)
+
+		FarmRegisterContext(ctx context.Context, farm Farm) (int64, error)
+		FarmUpdateContext(ctx context.Context, farm Farm) error
+		FarmListContext(ctx context.Context, tid int64, name string, page *Pager) (farms []Farm, err error)
+		FarmGetContext(ctx context.Context, id int64) (farm Farm, err error)
+		FarmsContext(ctx context.Context, cacheSize int) FarmIter
+		FarmAddIPContext(ctx context.Context, id int64, ip PublicIP) error
+		FarmDeleteIPContext(ctx context.Context, id int64, ipaddr string) error
+
+		NodeRegisterContext(ctx context.Context, node Node) error
+		NodeListContext(ctx context.Context, filter NodeFilter, pager *Pager) (nodes []Node, err error)
+		NodeGetContext(ctx context.Context, id string, proofs bool) (node Node, err error)
+		NodesContext(ctx context.Context, cacheSize int, proofs bool) NodeIter
+		NodeSetInterfacesContext(ctx context.Context, id string, ifaces []Iface) error
+		NodeSetPortsContext(ctx context.Context, id string, ports []uint) error
+		NodeSetPublicContext(ctx context.Context, id string, pub PublicIface) error
+		NodeUpdateUptimeContext(ctx context.Context, id string, uptime uint64) error
+		NodeUpdateUsedResourcesContext(ctx context.Context, id string, resources ResourceAmount, workloads WorkloadAmount) error
 	}
 
 	// Phonebook interface
+	//
+	// The Context variants of the methods stop waiting for the explorer when
+	// ctx is done, the others use context.Background()
 	Phonebook interface {
 		Create(user User) (int64, error)
 		Get(id int64) (User, error)
+		List(name, email string, page *Pager) (output []User, err error)
+		Validate(id int64, message, signature string) (bool, error)
 		GetUserByNameOrEmail(name, email string) (User, error)
 		UserExistsByNameOrEmail(name, email string) bool
 		UserHasSamePublicKey(u User, ident UserIdentity) bool
+
+		CreateContext(ctx context.Context, user User) (int64, error)
+		GetContext(ctx context.Context, id int64) (User, error)
+		ListContext(ctx context.Context, name, email string, page *Pager) (output []User, err error)
+		ValidateContext(ctx context.Context, id int64, message, signature string) (bool, error)
+		GetUserByNameOrEmailContext(ctx context.Context, name, email string) (User, error)
 	}
 
 	// Identity is used by the client to authenticate to the explorer API
@@ -530,20 +635,29 @@ func Page(page, size int) *Pager {
 }
 
 // NewClient creates a new client, if identity is not nil, it will be used
-// to authenticate requests against the server
-func NewClient(u string, id Identity) (*Client, error) {
-	h, err := newHTTPClient(u, id)
+// to authenticate requests against the server. Requests time out after
+// DefaultTimeout and are retried following DefaultRetryPolicy unless opts
+// say otherwise
+func NewClient(u string, id Identity, opts ...ClientOption) (*Client, error) {
+	h, err := newHTTPClient(u, id, opts...)
 	if err != nil {
 		return nil, err
 	}
 	cl := &Client{
 		Phonebook: &httpPhonebook{h},
 		Directory: &httpDirectory{h},
+		http:      h,
 	}
 
 	return cl, nil
 }
 
+// SetRetryPolicy changes how the requests of the client are retried, like
+// the WithRetryPolicy option
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.http.retry = policy
+}
+
 // Signer is a utility to easily sign payloads
 type Signer struct {
 	pair KeyPair
@@ -622,12 +736,18 @@ func (s *Signer) Sign(o ...interface{}) ([]byte, []byte, error) {
 	return msg, sig, err
 }
 
+var (
+	_ Directory = (*httpDirectory)(nil)
+	_ Phonebook = (*httpPhonebook)(nil)
+)
+
 type (
 	httpDirectory struct {
 		*httpClient
 	}
 
 	httpNodeIter struct {
+		ctx      context.Context
 		cl       *httpDirectory
 		proofs   bool
 		page     int
@@ -638,6 +758,7 @@ type (
 	}
 
 	httpFarmIter struct {
+		ctx      context.Context
 		cl       *httpDirectory
 		page     int
 		size     int
@@ -648,20 +769,32 @@ type (
 )
 
 func (d *httpDirectory) FarmRegister(farm Farm) (int64, error) {
+	return d.FarmRegisterContext(context.Background(), farm)
+}
+
+func (d *httpDirectory) FarmRegisterContext(ctx context.Context, farm Farm) (int64, error) {
 	var output struct {
 		ID int64 `json:"id"`
 	}
 
-	_, err := d.post(d.url("farms"), farm, &output, http.StatusCreated)
+	_, err := d.post(ctx, d.url("farms"), farm, &output, http.StatusCreated)
 	return output.ID, err
 }
 
 func (d *httpDirectory) FarmUpdate(farm Farm) error {
-	_, err := d.put(d.url("farms", fmt.Sprintf("%d", farm.ID)), farm, nil, http.StatusOK)
+	return d.FarmUpdateContext(context.Background(), farm)
+}
+
+func (d *httpDirectory) FarmUpdateContext(ctx context.Context, farm Farm) error {
+	_, err := d.put(ctx, d.url("farms", fmt.Sprintf("%d", farm.ID)), farm, nil, http.StatusOK)
 	return err
 }
 
 func (d *httpDirectory) FarmList(tid int64, name string, page *Pager) (farms []Farm, err error) {
+	return d.FarmListContext(context.Background(), tid, name, page)
+}
+
+func (d *httpDirectory) FarmListContext(ctx context.Context, tid int64, name string, page *Pager) (farms []Farm, err error) {
 	query := url.Values{}
 	page.apply(query)
 	if tid > 0 {
@@ -670,18 +803,26 @@ func (d *httpDirectory) FarmList(tid int64, name string, page *Pager) (farms []F
 	if len(name) != 0 {
 		query.Set("name", name)
 	}
-	_, err = d.get(d.url("farms"), query, &farms, http.StatusOK)
+	_, err = d.get(ctx, d.url("farms"), query, &farms, http.StatusOK)
 	return
 }
 
 func (d *httpDirectory) FarmGet(id int64) (farm Farm, err error) {
-	_, err = d.get(d.url("farms", fmt.Sprint(id)), nil, &farm, http.StatusOK)
+	return d.FarmGetContext(context.Background(), id)
+}
+
+func (d *httpDirectory) FarmGetContext(ctx context.Context, id int64) (farm Farm, err error) {
+	_, err = d.get(ctx, d.url("farms", fmt.Sprint(id)), nil, &farm, http.StatusOK)
 	return
 }
 
 func (d *httpDirectory) Farms(cacheSize int) FarmIter {
+	return d.FarmsContext(context.Background(), cacheSize)
+}
+
+func (d *httpDirectory) FarmsContext(ctx context.Context, cacheSize int) FarmIter {
 	// pages start at index 1
-	return &httpFarmIter{cl: d, size: cacheSize, page: 1}
+	return &httpFarmIter{ctx: ctx, cl: d, size: cacheSize, page: 1}
 }
 
 func (fi *httpFarmIter) Next() (*Farm, error) {
@@ -692,7 +833,7 @@ func (fi *httpFarmIter) Next() (*Farm, error) {
 		}
 		// pull new data in cache
 		pager := Page(fi.page, fi.size)
-		farms, err := fi.cl.FarmList(0, "", pager)
+		farms, err := fi.cl.FarmListContext(fi.ctx, 0, "", pager)
 		if err != nil {
 			return nil, errors.Wrap(err, "could not get farms")
 		}
@@ -712,57 +853,77 @@ func (fi *httpFarmIter) Next() (*Farm, error) {
 }
 
 func (d *httpDirectory) NodeRegister(node Node) error {
-	_, err := d.post(d.url("nodes"), node, nil, http.StatusCreated)
-	return err
+	return d.NodeRegisterContext(context.Background(), node)
 }
 
-// func (d *httpDirectory) NodeList(filter NodeFilter, pager *Pager) (nodes []Node, err error) {
-// 	query := url.Values{}
-// 	pager.apply(query)
-// 	filter.Apply(query)
-// 	_, err = d.get(d.url("nodes"), query, &nodes, http.StatusOK)
-// 	return
-// }
+func (d *httpDirectory) NodeRegisterContext(ctx context.Context, node Node) error {
+	_, err := d.post(ctx, d.url("nodes"), node, nil, http.StatusCreated)
+	return err
+}
 
 func (d *httpDirectory) NodeGet(id string, proofs bool) (node Node, err error) {
+	return d.NodeGetContext(context.Background(), id, proofs)
+}
+
+func (d *httpDirectory) NodeGetContext(ctx context.Context, id string, proofs bool) (node Node, err error) {
 	query := url.Values{}
 	query.Set("proofs", fmt.Sprint(proofs))
-	_, err = d.get(d.url("nodes", id), query, &node, http.StatusOK)
+	_, err = d.get(ctx, d.url("nodes", id), query, &node, http.StatusOK)
 	return
 }
 
 func (d *httpDirectory) NodeSetInterfaces(id string, ifaces []Iface) error {
-	_, err := d.post(d.url("nodes", id, "interfaces"), ifaces, nil, http.StatusCreated)
+	return d.NodeSetInterfacesContext(context.Background(), id, ifaces)
+}
+
+func (d *httpDirectory) NodeSetInterfacesContext(ctx context.Context, id string, ifaces []Iface) error {
+	_, err := d.post(ctx, d.url("nodes", id, "interfaces"), ifaces, nil, http.StatusCreated)
 	return err
 }
 
 func (d *httpDirectory) NodeSetPorts(id string, ports []uint) error {
+	return d.NodeSetPortsContext(context.Background(), id, ports)
+}
+
+func (d *httpDirectory) NodeSetPortsContext(ctx context.Context, id string, ports []uint) error {
 	var input struct {
 		P []uint `json:"ports"`
 	}
 	input.P = ports
 
-	_, err := d.post(d.url("nodes", id, "ports"), input, nil, http.StatusOK)
+	_, err := d.post(ctx, d.url("nodes", id, "ports"), input, nil, http.StatusOK)
 	return err
 }
 
 func (d *httpDirectory) NodeSetPublic(id string, pub PublicIface) error {
-	_, err := d.post(d.url("nodes", id, "configure_public"), pub, nil, http.StatusCreated)
+	return d.NodeSetPublicContext(context.Background(), id, pub)
+}
+
+func (d *httpDirectory) NodeSetPublicContext(ctx context.Context, id string, pub PublicIface) error {
+	_, err := d.post(ctx, d.url("nodes", id, "configure_public"), pub, nil, http.StatusCreated)
 	return err
 }
 
 func (d *httpDirectory) NodeUpdateUptime(id string, uptime uint64) error {
+	return d.NodeUpdateUptimeContext(context.Background(), id, uptime)
+}
+
+func (d *httpDirectory) NodeUpdateUptimeContext(ctx context.Context, id string, uptime uint64) error {
 	input := struct {
 		U uint64 `json:"uptime"`
 	}{
 		U: uptime,
 	}
 
-	_, err := d.post(d.url("nodes", id, "uptime"), input, nil, http.StatusOK)
+	_, err := d.post(ctx, d.url("nodes", id, "uptime"), input, nil, http.StatusOK)
 	return err
 }
 
 func (d *httpDirectory) NodeUpdateUsedResources(id string, resources ResourceAmount, workloads WorkloadAmount) error {
+	return d.NodeUpdateUsedResourcesContext(context.Background(), id, resources, workloads)
+}
+
+func (d *httpDirectory) NodeUpdateUsedResourcesContext(ctx context.Context, id string, resources ResourceAmount, workloads WorkloadAmount) error {
 	input := struct {
 		ResourceAmount
 		WorkloadAmount
@@ -770,28 +931,47 @@ func (d *httpDirectory) NodeUpdateUsedResources(id string, resources ResourceAmo
 		resources,
 		workloads,
 	}
-	_, err := d.post(d.url("nodes", id, "used_resources"), input, nil, http.StatusOK)
+	_, err := d.post(ctx, d.url("nodes", id, "used_resources"), input, nil, http.StatusOK)
 	return err
 }
+
 func (d *httpDirectory) FarmAddIP(id int64, ip PublicIP) error {
-	_, err := d.post(d.url("farms", fmt.Sprintf("%d/ip", id)), []PublicIP{ip}, nil, http.StatusOK)
+	return d.FarmAddIPContext(context.Background(), id, ip)
+}
+
+func (d *httpDirectory) FarmAddIPContext(ctx context.Context, id int64, ip PublicIP) error {
+	_, err := d.post(ctx, d.url("farms", fmt.Sprint(id), "ip"), []PublicIP{ip}, nil, http.StatusOK)
 	return err
 }
+
 func (d *httpDirectory) FarmDeleteIP(id int64, ipaddr string) error {
-	_, err := d.deleteWithBody(d.url("farms", fmt.Sprintf("%d/ip", id)), ipaddr, nil, http.StatusOK)
+	return d.FarmDeleteIPContext(context.Background(), id, ipaddr)
+}
+
+func (d *httpDirectory) FarmDeleteIPContext(ctx context.Context, id int64, ipaddr string) error {
+	_, err := d.deleteWithBody(ctx, d.url("farms", fmt.Sprint(id), "ip"), ipaddr, nil, http.StatusOK)
 	return err
 }
+
 func (d *httpDirectory) NodeList(filter NodeFilter, pager *Pager) (nodes []Node, err error) {
+	return d.NodeListContext(context.Background(), filter, pager)
+}
+
+func (d *httpDirectory) NodeListContext(ctx context.Context, filter NodeFilter, pager *Pager) (nodes []Node, err error) {
 	query := url.Values{}
 	pager.apply(query)
 	filter.Apply(query)
-	_, err = d.get(d.url("nodes"), query, &nodes, http.StatusOK)
+	_, err = d.get(ctx, d.url("nodes"), query, &nodes, http.StatusOK)
 	return
 }
 
 func (d *httpDirectory) Nodes(cacheSize int, proofs bool) NodeIter {
+	return d.NodesContext(context.Background(), cacheSize, proofs)
+}
+
+func (d *httpDirectory) NodesContext(ctx context.Context, cacheSize int, proofs bool) NodeIter {
 	// pages start at index 1
-	return &httpNodeIter{cl: d, size: cacheSize, page: 1, proofs: proofs}
+	return &httpNodeIter{ctx: ctx, cl: d, size: cacheSize, page: 1, proofs: proofs}
 }
 
 func (ni *httpNodeIter) Next() (*Node, error) {
@@ -803,7 +983,7 @@ func (ni *httpNodeIter) Next() (*Node, error) {
 		// pull new data in cache
 		pager := Page(ni.page, ni.size)
 		filter := NodeFilter{}.WithProofs(ni.proofs)
-		nodes, err := ni.cl.NodeList(filter, pager)
+		nodes, err := ni.cl.NodeListContext(ni.ctx, filter, pager)
 		if err != nil {
 			return nil, errors.Wrap(err, "could not get nodes")
 		}
@@ -827,8 +1007,12 @@ type httpPhonebook struct {
 }
 
 func (p *httpPhonebook) Create(user User) (int64, error) {
+	return p.CreateContext(context.Background(), user)
+}
+
+func (p *httpPhonebook) CreateContext(ctx context.Context, user User) (int64, error) {
 	var out User
-	if _, err := p.post(p.url("users"), user, &out); err != nil {
+	if _, err := p.post(ctx, p.url("users"), user, &out); err != nil {
 		return 0, err
 	}
 
@@ -836,6 +1020,10 @@ func (p *httpPhonebook) Create(user User) (int64, error) {
 }
 
 func (p *httpPhonebook) List(name, email string, page *Pager) (output []User, err error) {
+	return p.ListContext(context.Background(), name, email, page)
+}
+
+func (p *httpPhonebook) ListContext(ctx context.Context, name, email string, page *Pager) (output []User, err error) {
 	query := url.Values{}
 	page.apply(query)
 	if len(name) != 0 {
@@ -845,20 +1033,28 @@ func (p *httpPhonebook) List(name, email string, page *Pager) (output []User, er
 		query.Set("email", email)
 	}
 
-	_, err = p.get(p.url("users"), query, &output, http.StatusOK)
+	_, err = p.get(ctx, p.url("users"), query, &output, http.StatusOK)
 
 	return
 }
 
 func (p *httpPhonebook) Get(id int64) (user User, err error) {
-	_, err = p.get(p.url("users", fmt.Sprint(id)), nil, &user, http.StatusOK)
+	return p.GetContext(context.Background(), id)
+}
+
+func (p *httpPhonebook) GetContext(ctx context.Context, id int64) (user User, err error) {
+	_, err = p.get(ctx, p.url("users", fmt.Sprint(id)), nil, &user, http.StatusOK)
 	return
 }
 
 func (p *httpPhonebook) GetUserByNameOrEmail(name, email string) (User, error) {
+	return p.GetUserByNameOrEmailContext(context.Background(), name, email)
+}
+
+func (p *httpPhonebook) GetUserByNameOrEmailContext(ctx context.Context, name, email string) (User, error) {
 	pager := Page(1, 5)
 	u := User{}
-	users_list, err := p.List(name, email, pager)
+	users_list, err := p.ListContext(ctx, name, email, pager)
 	if err != nil {
 		return u, err
 	}
@@ -883,6 +1079,10 @@ func (p *httpPhonebook) UserHasSamePublicKey(u User, ident UserIdentity) bool {
 
 // Validate the signature of this message for the user, signature and message are hex encoded
 func (p *httpPhonebook) Validate(id int64, message, signature string) (bool, error) {
+	return p.ValidateContext(context.Background(), id, message, signature)
+}
+
+func (p *httpPhonebook) ValidateContext(ctx context.Context, id int64, message, signature string) (bool, error) {
 	var input struct {
 		S string `json:"signature"`
 		M string `json:"payload"`
@@ -894,7 +1094,7 @@ func (p *httpPhonebook) Validate(id int64, message, signature string) (bool, err
 		V bool `json:"is_valid"`
 	}
 
-	_, err := p.post(p.url("users", fmt.Sprint(id), "validate"), input, &output, http.StatusOK)
+	_, err := p.post(ctx, p.url("users", fmt.Sprint(id), "validate"), input, &output, http.StatusOK)
 	if err != nil {
 		return false, err
 	}
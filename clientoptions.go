@@ -0,0 +1,221 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultUserAgent is the User-Agent of the requests to the explorer
+	defaultUserAgent = "gofarmer"
+
+	// proxyEnv environment variable holding the proxy to reach the explorer
+	// through, the standard HTTPS_PROXY and HTTP_PROXY are used otherwise
+	proxyEnv = "GOFARMER_PROXY"
+	// caCertEnv environment variable holding a PEM file of the certificate
+	// authorities trusted on top of the system ones, for private explorers
+	caCertEnv = "GOFARMER_CA_CERT"
+)
+
+// clientConfig is what the options of NewClient set
+type clientConfig struct {
+	timeout   time.Duration
+	retry     RetryPolicy
+	transport http.RoundTripper
+	proxy     func(*http.Request) (*url.URL, error)
+	tls       *tls.Config
+	caCerts   []*x509.Certificate
+	userAgent string
+}
+
+// ClientOption configures the client created by NewClient
+type ClientOption func(*clientConfig) error
+
+// WithTimeout makes requests time out after timeout instead of
+// DefaultTimeout, 0 means no timeout and only the request context can abort them
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *clientConfig) error {
+		if timeout < 0 {
+			return fmt.Errorf("timeout can't be negative")
+		}
+		c.timeout = timeout
+		return nil
+	}
+}
+
+// WithRetryPolicy retries requests following policy instead of DefaultRetryPolicy
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *clientConfig) error {
+		c.retry = policy
+		return nil
+	}
+}
+
+// WithTransport sends the requests with rt instead of http.DefaultTransport.
+// It can be combined with WithProxy, WithTLSConfig and WithCACert only if rt
+// is an *http.Transport, which is then cloned
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *clientConfig) error {
+		if rt == nil {
+			return fmt.Errorf("transport can't be nil")
+		}
+		c.transport = rt
+		return nil
+	}
+}
+
+// WithProxy sends the requests through the proxy at u instead of the one set
+// in the environment, a nil u connects directly to the explorer
+func WithProxy(u *url.URL) ClientOption {
+	return func(c *clientConfig) error {
+		c.proxy = http.ProxyURL(u)
+		return nil
+	}
+}
+
+// WithTLSConfig uses cfg to connect to the explorer over https. The CAs of
+// WithCACert are added to the system ones whatever the order of the options,
+// so cfg can't set RootCAs too
+func WithTLSConfig(cfg *tls.Config) ClientOption {
+	return func(c *clientConfig) error {
+		if cfg == nil {
+			return fmt.Errorf("TLS config can't be nil")
+		}
+		c.tls = cfg.Clone()
+		return nil
+	}
+}
+
+// WithCACert trusts the certificate authorities of the PEM file at path on
+// top of the system ones, for explorers using a private CA
+func WithCACert(path string) ClientOption {
+	return func(c *clientConfig) error {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrap(err, "failed to read CA certificates")
+		}
+		var certs []*x509.Certificate
+		for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return errors.Wrapf(err, "invalid CA certificate in %s", path)
+			}
+			certs = append(certs, cert)
+		}
+		if len(certs) == 0 {
+			return fmt.Errorf("no PEM certificate found in %s", path)
+		}
+		c.caCerts = append(c.caCerts, certs...)
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent of the requests
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *clientConfig) error {
+		c.userAgent = userAgent
+		return nil
+	}
+}
+
+// newClientConfig returns the configuration of the client after opts
+func newClientConfig(opts ...ClientOption) (clientConfig, error) {
+	cfg := clientConfig{
+		timeout:   DefaultTimeout,
+		retry:     DefaultRetryPolicy,
+		userAgent: defaultUserAgent,
+	}
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return cfg, err
+		}
+	}
+	return cfg, nil
+}
+
+// tlsConfig returns the TLS config of the options with the CAs of WithCACert
+// added to the system ones, or nil if none is set
+func (c clientConfig) tlsConfig() (*tls.Config, error) {
+	if len(c.caCerts) == 0 {
+		return c.tls, nil
+	}
+
+	cfg := &tls.Config{}
+	if c.tls != nil {
+		if c.tls.RootCAs != nil {
+			return nil, fmt.Errorf("CA certificates can't be added to a TLS config with its own RootCAs")
+		}
+		cfg = c.tls.Clone()
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	for _, cert := range c.caCerts {
+		pool.AddCert(cert)
+	}
+	cfg.RootCAs = pool
+	return cfg, nil
+}
+
+// roundTripper returns the transport with the proxy and TLS config applied
+func (c clientConfig) roundTripper() (http.RoundTripper, error) {
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if c.proxy == nil && tlsConfig == nil {
+		return c.transport, nil
+	}
+
+	base := c.transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	transport, ok := base.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("proxy and TLS options need an *http.Transport, got %T", base)
+	}
+	transport = transport.Clone()
+	if c.proxy != nil {
+		transport.Proxy = c.proxy
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport, nil
+}
+
+// connectionOptions returns the options for a proxy url and a CA certificates
+// file, as given on the command line or in the environment. Empty values
+// are ignored and the proxy "direct" disables the environment proxy
+func connectionOptions(proxy, caCert string) ([]ClientOption, error) {
+	var opts []ClientOption
+	switch proxy = strings.TrimSpace(proxy); proxy {
+	case "":
+	case "direct":
+		opts = append(opts, WithProxy(nil))
+	default:
+		u, err := url.Parse(proxy)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy url %q", proxy)
+		}
+		opts = append(opts, WithProxy(u))
+	}
+	if caCert = strings.TrimSpace(caCert); caCert != "" {
+		opts = append(opts, WithCACert(caCert))
+	}
+	return opts, nil
+}
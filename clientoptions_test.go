@@ -0,0 +1,178 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// roundTripFunc is a transport answering requests without a server
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestClientUserAgent(t *testing.T) {
+	var agents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.UserAgent())
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	for _, opts := range [][]ClientOption{nil, {WithUserAgent("farm-tools/1.0")}} {
+		client, err := NewClient(server.URL, nil, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Directory.FarmList(0, "", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(agents) != 2 || agents[0] != defaultUserAgent || agents[1] != "farm-tools/1.0" {
+		t.Fatalf("unexpected user agents %q", agents)
+	}
+}
+
+func TestClientTransport(t *testing.T) {
+	var requested string
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requested = r.URL.String()
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Status:     "200 OK",
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       ioutil.NopCloser(strings.NewReader(`{"id": 7, "name": "myfarm"}`)),
+			Request:    r,
+		}, nil
+	})
+
+	client, err := NewClient("https://explorer.test", nil, WithTransport(transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	farm, err := client.Directory.FarmGet(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if farm.Name != "myfarm" || requested != "https://explorer.test/api/v1/farms/7" {
+		t.Fatalf("unexpected farm %+v from %s", farm, requested)
+	}
+
+	// only an *http.Transport can be given a proxy
+	if _, err := NewClient("https://explorer.test", nil, WithTransport(transport), WithProxy(&url.URL{Scheme: "http", Host: "proxy.test"})); err == nil {
+		t.Fatal("expected a proxy to be refused on a custom transport")
+	}
+}
+
+func TestClientProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.Write([]byte("[]"))
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	client, err := NewClient("http://explorer.test", nil, WithProxy(proxyURL), WithRetryPolicy(NoRetry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Directory.FarmList(0, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(proxied) != 1 || proxied[0] != "http://explorer.test/api/v1/farms" {
+		t.Fatalf("expected the request to go through the proxy, got %q", proxied)
+	}
+
+	opts, err := connectionOptions("direct", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err = NewClient("http://explorer.test", nil, append(opts, WithRetryPolicy(NoRetry))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Directory.FarmList(0, "", nil); errors.Cause(err) != ErrRequestFailure || len(proxied) != 1 {
+		t.Fatalf("expected a direct connection to fail, got %v", err)
+	}
+
+	if _, err := connectionOptions("not a url", ""); err == nil {
+		t.Fatal("expected an invalid proxy to be refused")
+	}
+}
+
+func TestClientCACert(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	// the server certificate is not trusted by the system
+	client, err := NewClient(server.URL, nil, WithRetryPolicy(NoRetry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Directory.FarmList(0, "", nil); errors.Cause(err) != ErrRequestFailure {
+		t.Fatalf("expected an unknown authority to be refused, got %v", err)
+	}
+
+	dir := t.TempDir()
+	caPath := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := ioutil.WriteFile(caPath, ca, 0600); err != nil {
+		t.Fatal(err)
+	}
+	opts, err := connectionOptions("", caPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err = NewClient(server.URL, nil, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Directory.FarmList(0, "", nil); err != nil {
+		t.Fatalf("expected the private CA to be trusted, got %v", err)
+	}
+
+	// the CA is kept whatever the order of the TLS options
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	for _, opts := range [][]ClientOption{
+		{WithCACert(caPath), WithTLSConfig(cfg)},
+		{WithTLSConfig(cfg), WithCACert(caPath)},
+	} {
+		client, err = NewClient(server.URL, nil, append(opts, WithRetryPolicy(NoRetry))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Directory.FarmList(0, "", nil); err != nil {
+			t.Fatalf("expected the private CA to be trusted with a TLS config, got %v", err)
+		}
+	}
+	if cfg.RootCAs != nil {
+		t.Fatal("expected the given TLS config not to be modified")
+	}
+	if _, err := NewClient(server.URL, nil, WithCACert(caPath), WithTLSConfig(&tls.Config{RootCAs: x509.NewCertPool()})); err == nil {
+		t.Fatal("expected CA certificates to be refused with a TLS config setting RootCAs")
+	}
+
+	emptyPath := filepath.Join(dir, "empty.pem")
+	if err := ioutil.WriteFile(emptyPath, []byte("no certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{emptyPath, filepath.Join(dir, "missing.pem")} {
+		if _, err := NewClient(server.URL, nil, WithCACert(path)); err == nil {
+			t.Errorf("expected %s to be refused", path)
+		}
+	}
+}
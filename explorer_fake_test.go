@@ -0,0 +1,506 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeExplorer is an in-process explorer keeping its users, farms and nodes
+// in memory. Requests changing farms and nodes have to be signed by their
+// owner like on the real explorer
+type fakeExplorer struct {
+	*httptest.Server
+
+	m      sync.Mutex
+	users  map[int64]User
+	farms  map[int64]Farm
+	nodes  map[string]Node
+	lastID int64
+	// failures are raw responses returned instead of handling the request,
+	// keyed by method and path
+	failures map[string]fakeResponse
+	// hangs are the requests never answered, keyed by method and path
+	hangs map[string]bool
+	// requests counts the requests by method and path
+	requests map[string]int
+
+	verifier *Verifier
+}
+
+type fakeResponse struct {
+	status int
+	body   string
+	header http.Header
+	// times is how many requests get the response, 0 for all of them
+	times int
+}
+
+func newFakeExplorer(t *testing.T) *fakeExplorer {
+	t.Helper()
+	f := &fakeExplorer{
+		users:    make(map[int64]User),
+		farms:    make(map[int64]Farm),
+		nodes:    make(map[string]Node),
+		failures: make(map[string]fakeResponse),
+		hangs:    make(map[string]bool),
+		requests: make(map[string]int),
+	}
+	f.verifier = NewVerifier(f.userKey)
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// testRetryPolicy retries like DefaultRetryPolicy without the long waits
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts: DefaultRetryPolicy.MaxAttempts,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    10 * time.Millisecond,
+}
+
+// client returns a client of the explorer signing its requests with id, if not nil
+func (f *fakeExplorer) client(t *testing.T, id Identity) *Client {
+	t.Helper()
+	cl, err := NewClient(f.URL, id, WithRetryPolicy(testRetryPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cl
+}
+
+// fail makes the explorer answer requests to method and path with status and body
+func (f *fakeExplorer) fail(method, path string, status int, body string) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.failures[method+" "+path] = fakeResponse{status: status, body: body}
+}
+
+// failTimes makes the explorer answer the next times requests to method and
+// path with status, header and body
+func (f *fakeExplorer) failTimes(times int, method, path string, status int, header http.Header, body string) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.failures[method+" "+path] = fakeResponse{status: status, body: body, header: header, times: times}
+}
+
+// hang makes the explorer never answer requests to method and path
+func (f *fakeExplorer) hang(method, path string) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.hangs[method+" "+path] = true
+}
+
+// count returns the number of requests received on method and path
+func (f *fakeExplorer) count(method, path string) int {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.requests[method+" "+path]
+}
+
+// addUser registers the user of ui directly, without going through the api
+func (f *fakeExplorer) addUser(ui *UserIdentity, name, email string) User {
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.lastID++
+	user := User{ID: f.lastID, Name: name, Email: email, Pubkey: hex.EncodeToString(ui.Key().PublicKey)}
+	f.users[user.ID] = user
+	ui.ThreebotID = user.ID
+	return user
+}
+
+// addNode adds a node to the explorer as if it registered itself
+func (f *fakeExplorer) addNode(node Node) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.nodes[node.NodeId] = node
+}
+
+// setFarm changes a farm directly, as another client would
+func (f *fakeExplorer) setFarm(farm Farm) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.farms[farm.ID] = farm
+}
+
+// userKey returns the key of a user, the lock has to be held
+func (f *fakeExplorer) userKey(id int64) (ed25519.PublicKey, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user %d not found", id)
+	}
+	return KeyFromHex(user.Pubkey)
+}
+
+// signer verifies the signature of r and returns the 3Bot ID that signed it
+func (f *fakeExplorer) signer(r *http.Request) (int64, error) {
+	return f.verifier.Verify(r)
+}
+
+func (f *fakeExplorer) serve(w http.ResponseWriter, r *http.Request) {
+	key := r.Method + " " + r.URL.Path
+	f.m.Lock()
+	f.requests[key]++
+	if f.hangs[key] {
+		f.m.Unlock()
+		// wait for the client to give up
+		<-r.Context().Done()
+		return
+	}
+	defer f.m.Unlock()
+
+	if failure, ok := f.failures[key]; ok {
+		if failure.times > 0 {
+			if failure.times--; failure.times == 0 {
+				delete(f.failures, key)
+			} else {
+				f.failures[key] = failure
+			}
+		}
+		for k, v := range failure.header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(failure.status)
+		fmt.Fprint(w, failure.body)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/")
+	route := r.Method + " " + parts[0]
+	if len(parts) > 1 {
+		route += "/{id}"
+	}
+	if len(parts) > 2 {
+		route += "/" + strings.Join(parts[2:], "/")
+	}
+
+	handlers := map[string]func(w http.ResponseWriter, r *http.Request, id string){
+		"POST users":                       f.createUser,
+		"GET users":                        f.listUsers,
+		"GET users/{id}":                   f.getUser,
+		"POST users/{id}/validate":         f.validateUser,
+		"POST farms":                       f.registerFarm,
+		"GET farms":                        f.listFarms,
+		"GET farms/{id}":                   f.getFarm,
+		"PUT farms/{id}":                   f.updateFarm,
+		"POST farms/{id}/ip":               f.addFarmIPs,
+		"DELETE farms/{id}/ip":             f.deleteFarmIP,
+		"GET nodes":                        f.listNodes,
+		"GET nodes/{id}":                   f.getNode,
+		"POST nodes/{id}/configure_public": f.setNodePublic,
+	}
+	handler, ok := handlers[route]
+	if !ok {
+		writeError(w, http.StatusNotFound, "no route %s", route)
+		return
+	}
+	id := ""
+	if len(parts) > 1 {
+		id = parts[1]
+	}
+	handler(w, r, id)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body: %s", err)
+		return false
+	}
+	return true
+}
+
+// page returns the items of the page asked in r out of n items, pages start at 1
+func page(r *http.Request, n int) (int, int) {
+	p, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+	if p < 1 {
+		p = 1
+	}
+	if size < 1 {
+		size = n
+	}
+	start, end := (p-1)*size, p*size
+	if start > n {
+		start = n
+	}
+	if end > n {
+		end = n
+	}
+	return start, end
+}
+
+func (f *fakeExplorer) createUser(w http.ResponseWriter, r *http.Request, _ string) {
+	var user User
+	if !decodeBody(w, r, &user) {
+		return
+	}
+	if user.Name == "" || user.Email == "" || user.Pubkey == "" {
+		writeError(w, http.StatusBadRequest, "name, email and pubkey are required")
+		return
+	}
+	for _, u := range f.users {
+		if u.Name == user.Name || u.Email == user.Email {
+			writeError(w, http.StatusConflict, "user with name or email already exists")
+			return
+		}
+	}
+	f.lastID++
+	user.ID = f.lastID
+	f.users[user.ID] = user
+	writeJSON(w, http.StatusCreated, user)
+}
+
+func (f *fakeExplorer) listUsers(w http.ResponseWriter, r *http.Request, _ string) {
+	name, email := r.URL.Query().Get("name"), r.URL.Query().Get("email")
+	users := make([]User, 0)
+	for _, u := range f.users {
+		if (name == "" || u.Name == name) && (email == "" || u.Email == email) {
+			users = append(users, u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	start, end := page(r, len(users))
+	writeJSON(w, http.StatusOK, users[start:end])
+}
+
+func (f *fakeExplorer) getUser(w http.ResponseWriter, r *http.Request, id string) {
+	tid, _ := strconv.ParseInt(id, 10, 64)
+	user, ok := f.users[tid]
+	if !ok {
+		writeError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+func (f *fakeExplorer) validateUser(w http.ResponseWriter, r *http.Request, id string) {
+	var input struct {
+		Signature string `json:"signature"`
+		Payload   string `json:"payload"`
+	}
+	if !decodeBody(w, r, &input) {
+		return
+	}
+	tid, _ := strconv.ParseInt(id, 10, 64)
+	key, err := f.userKey(tid)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "%s", err)
+		return
+	}
+	payload, err := hex.DecodeString(input.Payload)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "payload should be hex encoded")
+		return
+	}
+	signature, err := hex.DecodeString(input.Signature)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "signature should be hex encoded")
+		return
+	}
+	valid := ed25519.Verify(key, payload, signature)
+	writeJSON(w, http.StatusOK, map[string]bool{"is_valid": valid})
+}
+
+// farmOwner checks that r is signed by the owner of the farm id
+func (f *fakeExplorer) farmOwner(w http.ResponseWriter, r *http.Request, id string) (Farm, bool) {
+	signer, err := f.signer(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid signature: %s", err)
+		return Farm{}, false
+	}
+	farmID, _ := strconv.ParseInt(id, 10, 64)
+	farm, ok := f.farms[farmID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "farm not found")
+		return farm, false
+	}
+	if farm.ThreebotID != signer {
+		writeError(w, http.StatusUnauthorized, "farm %d is not owned by %d", farm.ID, signer)
+		return farm, false
+	}
+	return farm, true
+}
+
+func (f *fakeExplorer) registerFarm(w http.ResponseWriter, r *http.Request, _ string) {
+	signer, err := f.signer(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "invalid signature: %s", err)
+		return
+	}
+	var farm Farm
+	if !decodeBody(w, r, &farm) {
+		return
+	}
+	if farm.ThreebotID != signer {
+		writeError(w, http.StatusUnauthorized, "farm owner %d is not the signer %d", farm.ThreebotID, signer)
+		return
+	}
+	for _, x := range f.farms {
+		if x.Name == farm.Name {
+			writeError(w, http.StatusConflict, "farm with name %s already exists", farm.Name)
+			return
+		}
+	}
+	f.lastID++
+	farm.ID = f.lastID
+	f.farms[farm.ID] = farm
+	writeJSON(w, http.StatusCreated, map[string]int64{"id": farm.ID})
+}
+
+func (f *fakeExplorer) listFarms(w http.ResponseWriter, r *http.Request, _ string) {
+	owner, _ := strconv.ParseInt(r.URL.Query().Get("owner"), 10, 64)
+	name := r.URL.Query().Get("name")
+	farms := make([]Farm, 0)
+	for _, farm := range f.farms {
+		if (owner == 0 || farm.ThreebotID == owner) && (name == "" || farm.Name == name) {
+			farms = append(farms, farm)
+		}
+	}
+	sort.Slice(farms, func(i, j int) bool { return farms[i].ID < farms[j].ID })
+	start, end := page(r, len(farms))
+	writeJSON(w, http.StatusOK, farms[start:end])
+}
+
+func (f *fakeExplorer) getFarm(w http.ResponseWriter, r *http.Request, id string) {
+	farmID, _ := strconv.ParseInt(id, 10, 64)
+	farm, ok := f.farms[farmID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "farm not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, farm)
+}
+
+func (f *fakeExplorer) updateFarm(w http.ResponseWriter, r *http.Request, id string) {
+	current, ok := f.farmOwner(w, r, id)
+	if !ok {
+		return
+	}
+	var farm Farm
+	if !decodeBody(w, r, &farm) {
+		return
+	}
+	if farm.ID != current.ID {
+		writeError(w, http.StatusBadRequest, "farm ID can't be changed")
+		return
+	}
+	// public IPs are only managed through their own endpoints
+	farm.IPAddresses = current.IPAddresses
+	f.farms[farm.ID] = farm
+	writeJSON(w, http.StatusOK, nil)
+}
+
+func (f *fakeExplorer) addFarmIPs(w http.ResponseWriter, r *http.Request, id string) {
+	farm, ok := f.farmOwner(w, r, id)
+	if !ok {
+		return
+	}
+	var ips []PublicIP
+	if !decodeBody(w, r, &ips) {
+		return
+	}
+	for _, ip := range ips {
+		for _, x := range farm.IPAddresses {
+			if x.Address == ip.Address {
+				writeError(w, http.StatusConflict, "ip %s already exists", ip.Address)
+				return
+			}
+		}
+		farm.IPAddresses = append(farm.IPAddresses, ip)
+	}
+	f.farms[farm.ID] = farm
+	writeJSON(w, http.StatusOK, nil)
+}
+
+func (f *fakeExplorer) deleteFarmIP(w http.ResponseWriter, r *http.Request, id string) {
+	farm, ok := f.farmOwner(w, r, id)
+	if !ok {
+		return
+	}
+	var address string
+	if !decodeBody(w, r, &address) {
+		return
+	}
+	for i, ip := range farm.IPAddresses {
+		if ip.Address != address {
+			continue
+		}
+		if ip.Reserved() {
+			writeError(w, http.StatusBadRequest, "ip %s is reserved", address)
+			return
+		}
+		farm.IPAddresses = append(farm.IPAddresses[:i:i], farm.IPAddresses[i+1:]...)
+		f.farms[farm.ID] = farm
+		writeJSON(w, http.StatusOK, nil)
+		return
+	}
+	writeError(w, http.StatusNotFound, "ip %s not found", address)
+}
+
+// withoutProofs returns node without its proofs unless r asks for them
+func withoutProofs(r *http.Request, node Node) Node {
+	if r.URL.Query().Get("proofs") != "true" {
+		node.Proofs = nil
+	}
+	return node
+}
+
+func (f *fakeExplorer) listNodes(w http.ResponseWriter, r *http.Request, _ string) {
+	farm, _ := strconv.ParseInt(r.URL.Query().Get("farm"), 10, 64)
+	nodes := make([]Node, 0)
+	for _, node := range f.nodes {
+		if farm == 0 || node.FarmId == farm {
+			nodes = append(nodes, withoutProofs(r, node))
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].NodeId < nodes[j].NodeId })
+	start, end := page(r, len(nodes))
+	writeJSON(w, http.StatusOK, nodes[start:end])
+}
+
+func (f *fakeExplorer) getNode(w http.ResponseWriter, r *http.Request, id string) {
+	node, ok := f.nodes[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "node not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, withoutProofs(r, node))
+}
+
+func (f *fakeExplorer) setNodePublic(w http.ResponseWriter, r *http.Request, id string) {
+	node, ok := f.nodes[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "node not found")
+		return
+	}
+	if _, ok := f.farmOwner(w, r, fmt.Sprint(node.FarmId)); !ok {
+		return
+	}
+	var pub PublicIface
+	if !decodeBody(w, r, &pub) {
+		return
+	}
+	pub.Version++
+	node.PublicConfig = &pub
+	f.nodes[id] = node
+	writeJSON(w, http.StatusCreated, nil)
+}
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// testAddress is a valid stellar address
+const testAddress = "GAAZI4TCR3TY5OJHCTJC2A4QSY6CJWJH5IAJTGKIN2ER7LBNVKOCCWN7"
+
+// useMemoryStore keeps the seeds saved by the test in memory
+func useMemoryStore(t *testing.T) {
+	t.Helper()
+	store := seedStore
+	seedStore = newMemoryStore()
+	t.Cleanup(func() { seedStore = store })
+}
+
+// useConfigDir makes the network directories of the test temporary
+func useConfigDir(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, key := range []string{"HOME", "XDG_CONFIG_HOME"} {
+		old, set := os.LookupEnv(key)
+		os.Setenv(key, dir)
+		key := key
+		t.Cleanup(func() {
+			if set {
+				os.Setenv(key, old)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+	}
+}
+
+// newTestIdentity returns a new identity registered on the explorer
+func newTestIdentity(t *testing.T, f *fakeExplorer, name string) *UserIdentity {
+	t.Helper()
+	k, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ui := NewUserIdentity(k, 0)
+	f.addUser(ui, name+".3bot", name+"@example.com")
+	return ui
+}
+
+// newTestFarm registers a farm of ui on the explorer
+func newTestFarm(t *testing.T, f *fakeExplorer, ui *UserIdentity, name string) Farm {
+	t.Helper()
+	farm, err := registerFarm(f.client(t, ui), name, "farm@example.com", testAddress, nil, Location{}, int(ui.ThreebotID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return farm
+}
+
+func TestGenerateID(t *testing.T) {
+	useMemoryStore(t)
+	f := newFakeExplorer(t)
+
+	user, ui, err := generateID(f.URL, "bot.3bot", "bot@example.com", "bot.seed", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == 0 || ui.ThreebotID != user.ID {
+		t.Fatalf("expected the identity to get the ID of the new user, got user %d and identity %d", user.ID, ui.ThreebotID)
+	}
+
+	saved := &UserIdentity{}
+	if err := saved.Load("bot.seed"); err != nil {
+		t.Fatalf("failed to load the saved seed: %s", err)
+	}
+	if saved.ThreebotID != user.ID || saved.Mnemonic != ui.Mnemonic {
+		t.Fatalf("saved seed doesn't match the identity")
+	}
+
+	// registering again with the same words finds the existing user
+	again, _, err := generateID(f.URL, "bot.3bot", "bot@example.com", "other.seed", ui.Mnemonic, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != user.ID {
+		t.Fatalf("expected existing user %d, got %d", user.ID, again.ID)
+	}
+	if n := f.count(http.MethodPost, "/api/v1/users"); n != 1 {
+		t.Fatalf("expected a single user creation, got %d", n)
+	}
+
+	// another key can't take the name
+	if _, _, err := generateID(f.URL, "bot.3bot", "bot@example.com", "thief.seed", "", ""); err == nil || !strings.Contains(err.Error(), "public key doesn't match") {
+		t.Fatalf("expected a public key mismatch, got %v", err)
+	}
+}
+
+func TestRegisterFarm(t *testing.T) {
+	f := newFakeExplorer(t)
+	ui := newTestIdentity(t, f, "farmer")
+	client := f.client(t, ui)
+
+	wallets := []WalletAddress{{Asset: "FreeTFT", Address: testAddress}}
+	farm, err := registerFarm(client, " myfarm ", "farm@example.com", testAddress, wallets, Location{City: "Ghent", Country: "be"}, int(ui.ThreebotID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := client.Directory.FarmGet(farm.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "myfarm" || got.ThreebotID != ui.ThreebotID {
+		t.Fatalf("unexpected farm %+v", got)
+	}
+	if len(got.WalletAddresses) != 2 || farmTFTAddress(got) != testAddress {
+		t.Fatalf("unexpected wallets %+v", got.WalletAddresses)
+	}
+	if got.Location.Country != "Belgium" || got.Location.Continent != continentEurope {
+		t.Fatalf("expected the location to be normalized, got %+v", got.Location)
+	}
+
+	if _, err := registerFarm(client, "myfarm", "farm@example.com", testAddress, nil, Location{}, int(ui.ThreebotID)); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected a conflict on the farm name, got %v", err)
+	}
+
+	// invalid data is refused before anything is sent
+	before := f.count(http.MethodPost, "/api/v1/farms")
+	if _, err := registerFarm(client, "other", "farm@example.com", "S"+testAddress[1:], nil, Location{}, int(ui.ThreebotID)); err == nil {
+		t.Fatal("expected a secret key to be refused")
+	}
+	if _, err := registerFarm(client, "other", "farm@example.com", testAddress, nil, Location{Country: "Atlantis"}, int(ui.ThreebotID)); err == nil {
+		t.Fatal("expected an unknown country to be refused")
+	}
+	if n := f.count(http.MethodPost, "/api/v1/farms"); n != before {
+		t.Fatalf("expected no request for invalid farms, got %d", n-before)
+	}
+}
+
+func TestRegisterFarmSignature(t *testing.T) {
+	f := newFakeExplorer(t)
+	ui := newTestIdentity(t, f, "farmer")
+	other := newTestIdentity(t, f, "other")
+
+	for name, client := range map[string]*Client{
+		"unsigned":               f.client(t, nil),
+		"signed by another user": f.client(t, other),
+	} {
+		_, err := registerFarm(client, "myfarm", "farm@example.com", testAddress, nil, Location{}, int(ui.ThreebotID))
+		httpErr, ok := errors.Cause(err).(HTTPError)
+		if !ok || httpErr.Response().StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s: expected an unauthorized error, got %v", name, err)
+		}
+	}
+}
+
+func TestFarmUpdate(t *testing.T) {
+	f := newFakeExplorer(t)
+	ui := newTestIdentity(t, f, "farmer")
+	client := f.client(t, ui)
+	farm := newTestFarm(t, f, ui, "myfarm")
+	if _, err := addFarmIPs(client, farm.ID, []PublicIP{{Address: "185.69.166.10/24", Gateway: "185.69.166.1"}}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := client.Directory.FarmGet(farm.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	update, _, err := newFarmUpdate(client, loaded, func(farm *Farm) {
+		setFarmDetails(farm, "renamed", "", testAddress)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(update.Changes) != 1 || update.Changes[0].Field != "Name" {
+		t.Fatalf("expected only the name to change, got %v", update.Changes)
+	}
+	updated, err := update.Submit(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "renamed" || updated.Email != "farm@example.com" || len(updated.IPAddresses) != 1 {
+		t.Fatalf("expected only the name to change, got %+v", updated)
+	}
+
+	// an edit made on an outdated farm is refused
+	changed := updated
+	changed.Email = "new@example.com"
+	f.setFarm(changed)
+	_, current, err := newFarmUpdate(client, updated, func(farm *Farm) { farm.Name = "again" })
+	if errors.Cause(err) != ErrFarmConflict {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	if current.Email != "new@example.com" {
+		t.Fatalf("expected the conflict to return the explorer farm, got %+v", current)
+	}
+
+	// and so is a farm changed between the preview and the submit
+	update, _, err = newFarmUpdate(client, current, func(farm *Farm) { farm.Name = "again" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed.Email = "newer@example.com"
+	f.setFarm(changed)
+	if _, err := update.Submit(client); errors.Cause(err) != ErrFarmConflict {
+		t.Fatalf("expected a conflict on submit, got %v", err)
+	}
+
+	// only the owner can update the farm
+	other := newTestIdentity(t, f, "other")
+	update, _, err = newFarmUpdate(f.client(t, other), changed, func(farm *Farm) { farm.Name = "stolen" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := update.Submit(f.client(t, other)); err == nil {
+		t.Fatal("expected another user to be refused")
+	}
+}
+
+func TestFarmsIterator(t *testing.T) {
+	for _, count := range []int{0, 5, 6, 7} {
+		t.Run(fmt.Sprint(count), func(t *testing.T) {
+			f := newFakeExplorer(t)
+			ui := newTestIdentity(t, f, "farmer")
+			for i := 0; i < count; i++ {
+				newTestFarm(t, f, ui, fmt.Sprintf("farm%d", i))
+			}
+
+			iter := f.client(t, nil).Directory.Farms(3)
+			seen := make(map[int64]bool)
+			for {
+				farm, err := iter.Next()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if farm == nil {
+					break
+				}
+				if seen[farm.ID] {
+					t.Fatalf("farm %d returned twice", farm.ID)
+				}
+				seen[farm.ID] = true
+			}
+			if len(seen) != count {
+				t.Fatalf("expected %d farms, got %d", count, len(seen))
+			}
+			// a short page ends the iteration without asking for the next one
+			if pages, expected := f.count(http.MethodGet, "/api/v1/farms"), count/3+1; pages != expected {
+				t.Fatalf("expected %d pages to be fetched, got %d", expected, pages)
+			}
+		})
+	}
+}
+
+func TestNodesIterator(t *testing.T) {
+	f := newFakeExplorer(t)
+	for i := 0; i < 5; i++ {
+		f.addNode(Node{NodeId: fmt.Sprintf("node%d", i), FarmId: 1, Proofs: []Proof{{HardwareHash: "hash"}}})
+	}
+
+	for _, proofs := range []bool{false, true} {
+		iter := f.client(t, nil).Directory.Nodes(2, proofs)
+		count := 0
+		for {
+			node, err := iter.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if node == nil {
+				break
+			}
+			if (len(node.Proofs) != 0) != proofs {
+				t.Fatalf("proofs=%v: unexpected proofs %v on node %s", proofs, node.Proofs, node.NodeId)
+			}
+			count++
+		}
+		if count != 5 {
+			t.Fatalf("expected 5 nodes, got %d", count)
+		}
+	}
+}
+
+func TestListAllNodesAndNames(t *testing.T) {
+	f := newFakeExplorer(t)
+	for i := 0; i < 45; i++ {
+		f.addNode(Node{NodeId: fmt.Sprintf("node%02d", i), FarmId: 1})
+	}
+	f.addNode(Node{NodeId: "elsewhere", FarmId: 2})
+
+	nodes, names, err := ListAllNodesAndNames(f.client(t, nil), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 45 || len(names) != 45 {
+		t.Fatalf("expected the 45 nodes of the farm, got %d nodes and %d names", len(nodes), len(names))
+	}
+	for i, n := range nodes {
+		if n.FarmId != 1 || names[i] != n.NodeId {
+			t.Fatalf("unexpected node %d: %+v named %s", i, n, names[i])
+		}
+	}
+}
+
+func TestProcessErrors(t *testing.T) {
+	f := newFakeExplorer(t)
+	client := f.client(t, nil)
+
+	// the error message of the explorer is returned with the response
+	_, err := client.Directory.FarmGet(42)
+	httpErr, ok := err.(HTTPError)
+	if !ok {
+		t.Fatalf("expected an HTTPError, got %T %v", err, err)
+	}
+	if httpErr.Response().StatusCode != http.StatusNotFound || !strings.Contains(err.Error(), "farm not found") || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("unexpected error %q", err)
+	}
+
+	// an error that can't be decoded still carries the response
+	f.fail(http.MethodGet, "/api/v1/farms/1", http.StatusBadGateway, "<html>bad gateway</html>")
+	_, err = client.Directory.FarmGet(1)
+	if httpErr, ok := errors.Cause(err).(HTTPError); !ok || httpErr.Response().StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected a wrapped HTTPError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "failed to load error while processing invalid return code") {
+		t.Fatalf("unexpected error %q", err)
+	}
+
+	// so does an output that can't be decoded
+	f.fail(http.MethodGet, "/api/v1/farms/2", http.StatusOK, "not json")
+	_, err = client.Directory.FarmGet(2)
+	if _, ok := err.(HTTPError); !ok || !strings.Contains(err.Error(), "failed to load output") {
+		t.Fatalf("expected an output HTTPError, got %v", err)
+	}
+
+	// an unexpected success code is an error too
+	f.fail(http.MethodGet, "/api/v1/farms/3", http.StatusAccepted, `{"error": "accepted"}`)
+	if _, err = client.Directory.FarmGet(3); err == nil {
+		t.Fatal("expected an error on an unexpected status code")
+	}
+
+	// the explorer can't be reached
+	f.Close()
+	if _, err = client.Directory.FarmGet(1); errors.Cause(err) != ErrRequestFailure {
+		t.Fatalf("expected a request failure, got %v", err)
+	}
+}
+
+func TestFarmIPs(t *testing.T) {
+	f := newFakeExplorer(t)
+	ui := newTestIdentity(t, f, "farmer")
+	client := f.client(t, ui)
+	farm := newTestFarm(t, f, ui, "myfarm")
+
+	addresses, err := expandIPRange("185.69.166.10/24", "185.69.166.12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ips := make([]PublicIP, 0, len(addresses))
+	for _, address := range addresses {
+		ips = append(ips, PublicIP{Address: address, Gateway: "185.69.166.1"})
+	}
+	farm, err = addFarmIPs(client, farm.ID, ips)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// adding them again skips the existing ones
+	if farm, err = addFarmIPs(client, farm.ID, ips); err != nil || len(farm.IPAddresses) != 3 {
+		t.Fatalf("expected 3 ips, got %v (%v)", farm.IPAddresses, err)
+	}
+
+	reserved := farm
+	reserved.IPAddresses[0].ReservationID = 7
+	f.setFarm(reserved)
+	if _, err := deleteFarmIP(client, farm.ID, addresses[0]); err == nil {
+		t.Fatal("expected a reserved ip to be kept")
+	}
+	if farm, err = deleteFarmIP(client, farm.ID, addresses[1]); err != nil || len(farm.IPAddresses) != 2 {
+		t.Fatalf("expected 2 ips left, got %v (%v)", farm.IPAddresses, err)
+	}
+}
+
+func TestFarmTransfer(t *testing.T) {
+	useConfigDir(t)
+	f := newFakeExplorer(t)
+	ui := newTestIdentity(t, f, "farmer")
+	buyer := newTestIdentity(t, f, "buyer")
+	client := f.client(t, ui)
+	farm := newTestFarm(t, f, ui, "myfarm")
+	n := Network{Name: "test", URL: f.URL}
+
+	transfer, err := newFarmTransfer(client, n, ui, farm, buyer.ThreebotID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transfer.ToName != "buyer.3bot" {
+		t.Fatalf("expected the new owner to be resolved, got %q", transfer.ToName)
+	}
+	if err := checkTransferConfirmation(transfer, "otherfarm"); err == nil {
+		t.Fatal("expected a wrong farm name to be refused")
+	}
+
+	updated, err := transfer.Submit(client, n, farm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ThreebotID != buyer.ThreebotID {
+		t.Fatalf("expected the farm to be owned by %d, got %d", buyer.ThreebotID, updated.ThreebotID)
+	}
+
+	// the farm can't be transferred again by its old owner
+	if _, err := newFarmTransfer(client, n, ui, updated, ui.ThreebotID); err == nil {
+		t.Fatal("expected the old owner to be refused")
+	}
+
+	transfers, err := readTransferLog(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 1 || transfers[0].Status != transferDone || transfers[0].Signature == "" {
+		t.Fatalf("unexpected transfer log %+v", transfers)
+	}
+}
+
+func TestNodePublicConfig(t *testing.T) {
+	f := newFakeExplorer(t)
+	ui := newTestIdentity(t, f, "farmer")
+	farm := newTestFarm(t, f, ui, "myfarm")
+	f.addNode(Node{NodeId: "node1", FarmId: farm.ID, Ifaces: []Iface{{Name: "eth0"}, {Name: "eth1"}}})
+
+	node, err := f.client(t, nil).Directory.NodeGet("node1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := parsePublicConfig("eth1", "macvlan", "185.69.166.10/24", "185.69.166.1", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := setNodePublicConfig(f.client(t, newTestIdentity(t, f, "other")), node, pub); err == nil {
+		t.Fatal("expected a user not owning the farm to be refused")
+	}
+	node, err = setNodePublicConfig(f.client(t, ui), node, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.PublicConfig == nil || node.PublicConfig.Master != "eth1" || node.PublicConfig.Ipv4 != "185.69.166.10/24" {
+		t.Fatalf("unexpected public config %+v", node.PublicConfig)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	f := newFakeExplorer(t)
+	f.hang(http.MethodGet, "/api/v1/farms/1")
+
+	client, err := NewClient(f.URL, nil, WithTimeout(50*time.Millisecond), WithRetryPolicy(testRetryPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Directory.FarmGet(1); errors.Cause(err) != ErrRequestFailure {
+		t.Fatalf("expected a request failure, got %v", err)
+	}
+}
+
+func TestClientContext(t *testing.T) {
+	f := newFakeExplorer(t)
+	f.hang(http.MethodGet, "/api/v1/farms/1")
+	f.hang(http.MethodGet, "/api/v1/nodes")
+	client := f.client(t, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Directory.FarmGetContext(ctx, 1); errors.Cause(err) != context.DeadlineExceeded {
+		t.Fatalf("expected the deadline to be exceeded, got %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	if _, _, err := ListAllNodesAndNamesContext(ctx, client, 1); errors.Cause(err) != context.Canceled {
+		t.Fatalf("expected the listing to be canceled, got %v", err)
+	}
+	if _, err := client.Directory.NodesContext(ctx, 10, false).Next(); errors.Cause(err) != context.Canceled {
+		t.Fatalf("expected the iteration to be canceled, got %v", err)
+	}
+
+	// requests not hanging still go through
+	newTestFarm(t, f, newTestIdentity(t, f, "farmer"), "myfarm")
+	if _, err := client.Directory.FarmsContext(context.Background(), 10).Next(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConvertLegacySeed(t *testing.T) {
+	useMemoryStore(t)
+	f := newFakeExplorer(t)
+	ui := newTestIdentity(t, f, "farmer")
+
+	// the legacy seed is only in the store, not on the file system
+	const path = "/nonexistent/default.seed"
+	legacy, err := WriteBytes(SeedVersion1, ui.Key().PrivateKey.Seed())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := seedStore.Set(path, legacy); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&UserIdentity{}).Load(path); err != ErrLegacySeed {
+		t.Fatalf("expected a legacy seed, got %v", err)
+	}
+
+	converted, backup, err := convertLegacySeed(context.Background(), f.client(t, nil), path, path, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if converted.ThreebotID != ui.ThreebotID {
+		t.Fatalf("expected 3Bot %d to be found by public key, got %d", ui.ThreebotID, converted.ThreebotID)
+	}
+	if saved, err := seedStore.Get(backup); err != nil || string(saved) != string(legacy) {
+		t.Fatalf("expected the legacy seed to be kept in the store at %s, got %v", backup, err)
+	}
+	loaded := &UserIdentity{}
+	if err := loaded.Load(path); err != nil || loaded.ThreebotID != ui.ThreebotID {
+		t.Fatalf("expected the converted seed to load, got %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// maxIPRange is the maximum number of addresses added at once from a range
+const maxIPRange = 256
+
+// String returns the address and gateway of the ip, and the reservation using it if any
+func (ip PublicIP) String() string {
+	s := fmt.Sprintf("%s via %s", ip.Address, ip.Gateway)
+	if ip.Reserved() {
+		s += fmt.Sprintf(" (reserved by %d)", ip.ReservationID)
+	}
+	return s
+}
+
+// Reserved reports whether a workload is using the ip, reserved ips can't be removed
+func (ip PublicIP) Reserved() bool {
+	return ip.ReservationID != 0
+}
+
+// validatePublicIP checks that address is an ip in CIDR notation and gateway
+// an ip of the same family inside its network
+func validatePublicIP(address, gateway string) error {
+	ip, ipnet, err := net.ParseCIDR(strings.TrimSpace(address))
+	if err != nil {
+		return fmt.Errorf("address %q should be in CIDR notation, e.g. 185.69.166.10/24", address)
+	}
+	gw := net.ParseIP(strings.TrimSpace(gateway))
+	if gw == nil {
+		return fmt.Errorf("gateway %q is not a valid ip", gateway)
+	}
+	if (ip.To4() == nil) != (gw.To4() == nil) {
+		return fmt.Errorf("address %s and gateway %s are not of the same ip family", address, gateway)
+	}
+	if !ipnet.Contains(gw) {
+		return fmt.Errorf("gateway %s is not in the network %s", gateway, ipnet)
+	}
+	if ip.Equal(gw) {
+		return fmt.Errorf("address %s can't be its own gateway", address)
+	}
+
+	// point to point networks (/31, /32) have no network nor broadcast address
+	if ones, bits := ipnet.Mask.Size(); ones >= bits-1 {
+		return nil
+	}
+	if ip.Equal(ipnet.IP) {
+		return fmt.Errorf("address %s is the network address", address)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		broadcast := make(net.IP, 4)
+		for i := range ip4 {
+			broadcast[i] = ipnet.IP.To4()[i] | ^ipnet.Mask[i]
+		}
+		if ip4.Equal(broadcast) {
+			return fmt.Errorf("address %s is the broadcast address", address)
+		}
+	}
+	return nil
+}
+
+// expandIPRange returns the addresses from first (in CIDR notation) to last,
+// both included, all with the prefix length of first. Only ipv4 ranges are supported
+func expandIPRange(first, last string) ([]string, error) {
+	start, ipnet, err := net.ParseCIDR(strings.TrimSpace(first))
+	if err != nil || start.To4() == nil {
+		return nil, fmt.Errorf("first address %q should be an ipv4 in CIDR notation, e.g. 185.69.166.10/24", first)
+	}
+	end := net.ParseIP(strings.TrimSpace(last))
+	if end == nil || end.To4() == nil {
+		return nil, fmt.Errorf("last address %q is not a valid ipv4", last)
+	}
+	if !ipnet.Contains(end) {
+		return nil, fmt.Errorf("last address %s is not in the network %s", last, ipnet)
+	}
+	if bytes.Compare(start.To4(), end.To4()) > 0 {
+		return nil, fmt.Errorf("last address %s comes before %s", last, start)
+	}
+
+	ones, _ := ipnet.Mask.Size()
+	from, to := binary.BigEndian.Uint32(start.To4()), binary.BigEndian.Uint32(end.To4())
+	if to-from >= maxIPRange {
+		return nil, fmt.Errorf("range is too large, at most %d addresses can be added at once", maxIPRange)
+	}
+
+	addresses := make([]string, 0, to-from+1)
+	// count from 0 so a range ending at 255.255.255.255 doesn't wrap around
+	for n := uint32(0); n <= to-from; n++ {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, from+n)
+		addresses = append(addresses, fmt.Sprintf("%s/%d", ip, ones))
+	}
+	return addresses, nil
+}
+
+// addFarmIPs validates all the ips before adding them to the farm, ips the
+// farm already has are skipped. The farm is returned as reloaded from the
+// explorer, even if adding one of the ips failed
+func addFarmIPs(expclient *Client, farmID int64, ips []PublicIP) (Farm, error) {
+	for _, ip := range ips {
+		if err := validatePublicIP(ip.Address, ip.Gateway); err != nil {
+			return Farm{}, err
+		}
+	}
+
+	farm, err := expclient.Directory.FarmGet(farmID)
+	if err != nil {
+		return farm, errors.Wrapf(err, "failed to get farm %d", farmID)
+	}
+	existing := make(map[string]bool, len(farm.IPAddresses))
+	for _, ip := range farm.IPAddresses {
+		existing[ip.Address] = true
+	}
+
+	var addErr error
+	for _, ip := range ips {
+		if existing[ip.Address] {
+			continue
+		}
+		if err := expclient.Directory.FarmAddIP(farmID, ip); err != nil {
+			addErr = errors.Wrapf(err, "failed to add ip %s", ip.Address)
+			break
+		}
+	}
+
+	farm, err = expclient.Directory.FarmGet(farmID)
+	if addErr != nil {
+		return farm, addErr
+	}
+	return farm, errors.Wrapf(err, "failed to get farm %d", farmID)
+}
+
+// deleteFarmIP removes address from the farm unless it's reserved and
+// returns the farm as reloaded from the explorer
+func deleteFarmIP(expclient *Client, farmID int64, address string) (Farm, error) {
+	farm, err := expclient.Directory.FarmGet(farmID)
+	if err != nil {
+		return farm, errors.Wrapf(err, "failed to get farm %d", farmID)
+	}
+
+	found := false
+	for _, ip := range farm.IPAddresses {
+		if ip.Address != address {
+			continue
+		}
+		if ip.Reserved() {
+			return farm, fmt.Errorf("ip %s is reserved by %d and can't be removed", address, ip.ReservationID)
+		}
+		found = true
+	}
+	if !found {
+		return farm, fmt.Errorf("farm %d has no ip %s", farmID, address)
+	}
+
+	if err := expclient.Directory.FarmDeleteIP(farmID, address); err != nil {
+		return farm, errors.Wrapf(err, "failed to remove ip %s", address)
+	}
+
+	farm, err = expclient.Directory.FarmGet(farmID)
+	return farm, errors.Wrapf(err, "failed to get farm %d", farmID)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidatePublicIP(t *testing.T) {
+	for _, test := range []struct {
+		address, gateway string
+		valid            bool
+	}{
+		{"185.69.166.10/24", "185.69.166.1", true},
+		{"2a02:1802:5e::10/64", "2a02:1802:5e::1", true},
+		{"185.69.166.10/24", "185.69.167.1", false},
+		{"185.69.166.10/24", "185.69.166.10", false},
+		{"185.69.166.0/24", "185.69.166.1", false},
+		{"185.69.166.255/24", "185.69.166.1", false},
+		{"185.69.166.10/24", "2a02:1802:5e::1", false},
+		// farm IPs need a gateway in their network, link-local ones included
+		{"2a02:1802:5e::10/64", "fe80::1", false},
+		{"185.69.166.10", "185.69.166.1", false},
+	} {
+		if err := validatePublicIP(test.address, test.gateway); (err == nil) != test.valid {
+			t.Errorf("%s gw %s: expected valid to be %v, got %v", test.address, test.gateway, test.valid, err)
+		}
+	}
+}
+
+func TestExpandIPRange(t *testing.T) {
+	for _, test := range []struct {
+		first, last string
+		expected    []string
+	}{
+		{"185.69.166.10/24", "185.69.166.10", []string{"185.69.166.10/24"}},
+		{"185.69.166.10/24", "185.69.166.12", []string{"185.69.166.10/24", "185.69.166.11/24", "185.69.166.12/24"}},
+		{"10.0.0.255/16", "10.0.1.1", []string{"10.0.0.255/16", "10.0.1.0/16", "10.0.1.1/16"}},
+		{"255.255.255.254/24", "255.255.255.255", []string{"255.255.255.254/24", "255.255.255.255/24"}},
+		{"255.255.255.255/32", "255.255.255.255", []string{"255.255.255.255/32"}},
+		{"185.69.166.10/24", "185.69.166.9", nil},
+		{"185.69.166.10/24", "185.69.167.1", nil},
+		{"185.69.166.10", "185.69.166.12", nil},
+		{"2a02:1802::10/64", "2a02:1802::12", nil},
+		{"10.0.0.1/8", "10.255.255.255", nil},
+	} {
+		addresses, err := expandIPRange(test.first, test.last)
+		if test.expected == nil {
+			if err == nil {
+				t.Errorf("%s - %s: expected an error, got %v", test.first, test.last, addresses)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(addresses, test.expected) {
+			t.Errorf("%s - %s: expected %v, got %v (%v)", test.first, test.last, test.expected, addresses, err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrFarmConflict is returned when a farm changed on the explorer since it was loaded
+var ErrFarmConflict = fmt.Errorf("farm changed on the explorer since it was loaded, reload it and try again")
+
+// farmChange is a field of a farm changed by an update
+type farmChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+func (c farmChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Field, c.Old, c.New)
+}
+
+// diffFarms returns the fields that differ between old and new. Values are
+// compared by their printed form so nil and empty lists are the same
+func diffFarms(old, new Farm) []farmChange {
+	changes := make([]farmChange, 0)
+	o, n := reflect.ValueOf(old), reflect.ValueOf(new)
+	for i := 0; i < o.NumField(); i++ {
+		before, after := fmt.Sprintf("%+v", o.Field(i).Interface()), fmt.Sprintf("%+v", n.Field(i).Interface())
+		if before != after {
+			changes = append(changes, farmChange{Field: o.Type().Field(i).Name, Old: before, New: after})
+		}
+	}
+	return changes
+}
+
+// formatFarmChanges returns the changes one per line
+func formatFarmChanges(changes []farmChange) string {
+	lines := make([]string, 0, len(changes))
+	for _, c := range changes {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cloneFarm returns a deep copy of farm so edits don't touch its lists
+func cloneFarm(farm Farm) (Farm, error) {
+	var clone Farm
+	data, err := json.Marshal(farm)
+	if err != nil {
+		return clone, err
+	}
+	err = json.Unmarshal(data, &clone)
+	return clone, err
+}
+
+// farmUpdate is an edit of a farm applied on top of its explorer version
+type farmUpdate struct {
+	// loaded is the farm the edit was made on
+	loaded Farm
+	// updated is the farm that will be sent
+	updated Farm
+	// Changes are the fields changed by the edit
+	Changes []farmChange
+}
+
+// checkFarmUnchanged returns the farm from the explorer, or ErrFarmConflict
+// with the farm if it's not the same as loaded anymore
+func checkFarmUnchanged(expclient *Client, loaded Farm) (Farm, error) {
+	current, err := expclient.Directory.FarmGet(loaded.ID)
+	if err != nil {
+		return current, errors.Wrapf(err, "failed to get farm %d", loaded.ID)
+	}
+	if changes := diffFarms(loaded, current); len(changes) != 0 {
+		return current, errors.Wrap(ErrFarmConflict, formatFarmChanges(changes))
+	}
+	return current, nil
+}
+
+// newFarmUpdate gets the farm loaded from the explorer and applies edit on it,
+// only the fields set by edit are changed. ErrFarmConflict is returned with
+// the explorer farm if it changed since it was loaded
+func newFarmUpdate(expclient *Client, loaded Farm, edit func(farm *Farm)) (*farmUpdate, Farm, error) {
+	current, err := checkFarmUnchanged(expclient, loaded)
+	if err != nil {
+		return nil, current, err
+	}
+
+	updated, err := cloneFarm(current)
+	if err != nil {
+		return nil, current, err
+	}
+	edit(&updated)
+	// the ID and owner checks are done by the explorer, the ID can't be edited
+	updated.ID = current.ID
+
+	return &farmUpdate{
+		loaded:  current,
+		updated: updated,
+		Changes: diffFarms(current, updated),
+	}, current, nil
+}
+
+// Submit sends the update if the farm is still unchanged on the explorer and
+// returns the farm as reloaded from the explorer
+func (u *farmUpdate) Submit(expclient *Client) (Farm, error) {
+	if len(u.Changes) == 0 {
+		return u.loaded, nil
+	}
+	if current, err := checkFarmUnchanged(expclient, u.loaded); err != nil {
+		return current, err
+	}
+
+	if err := expclient.Directory.FarmUpdate(u.updated); err != nil {
+		return u.loaded, errors.Wrap(err, "failed to update farm")
+	}
+
+	farm, err := expclient.Directory.FarmGet(u.updated.ID)
+	if err != nil {
+		return u.updated, errors.Wrapf(err, "failed to get farm %d", u.updated.ID)
+	}
+	return farm, nil
+}
@@ -14,6 +14,7 @@ import (
 // Version History:
 //   1.0.0: seed binary directly encoded
 //   1.1.0: json with key mnemonic and threebot id
+//   1.2.0: 1.1.0 json encrypted with a passphrase
 
 // KeyPair holds a public and private side of an ed25519 key pair
 type KeyPair struct {
@@ -52,7 +53,7 @@ func LoadSeed(path string) ([]byte, error) {
 	if versioned.IsNotVersioned(err) {
 		// this is a compatibility code for seed files
 		// in case it does not have any version information
-		versioned.WriteFile(path, SeedVersionLatest, seed, 0400)
+		versioned.WriteFile(path, SeedVersion1, seed, 0400)
 		version = SeedVersion1
 	} else if err != nil {
 		return nil, err
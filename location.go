@@ -0,0 +1,416 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	continentAfrica       = "Africa"
+	continentAntarctica   = "Antarctica"
+	continentAsia         = "Asia"
+	continentEurope       = "Europe"
+	continentNorthAmerica = "North America"
+	continentOceania      = "Oceania"
+	continentSouthAmerica = "South America"
+)
+
+// country is an entry of the offline countries table
+type country struct {
+	Code      string
+	Name      string
+	Continent string
+}
+
+// countries is the offline table used to validate farm locations, names are
+// the ones reported by the nodes
+var countries = []country{
+	{"AF", "Afghanistan", continentAsia},
+	{"AX", "Aland Islands", continentEurope},
+	{"AL", "Albania", continentEurope},
+	{"DZ", "Algeria", continentAfrica},
+	{"AS", "American Samoa", continentOceania},
+	{"AD", "Andorra", continentEurope},
+	{"AO", "Angola", continentAfrica},
+	{"AI", "Anguilla", continentNorthAmerica},
+	{"AQ", "Antarctica", continentAntarctica},
+	{"AG", "Antigua and Barbuda", continentNorthAmerica},
+	{"AR", "Argentina", continentSouthAmerica},
+	{"AM", "Armenia", continentAsia},
+	{"AW", "Aruba", continentNorthAmerica},
+	{"AU", "Australia", continentOceania},
+	{"AT", "Austria", continentEurope},
+	{"AZ", "Azerbaijan", continentAsia},
+	{"BS", "Bahamas", continentNorthAmerica},
+	{"BH", "Bahrain", continentAsia},
+	{"BD", "Bangladesh", continentAsia},
+	{"BB", "Barbados", continentNorthAmerica},
+	{"BY", "Belarus", continentEurope},
+	{"BE", "Belgium", continentEurope},
+	{"BZ", "Belize", continentNorthAmerica},
+	{"BJ", "Benin", continentAfrica},
+	{"BM", "Bermuda", continentNorthAmerica},
+	{"BT", "Bhutan", continentAsia},
+	{"BO", "Bolivia", continentSouthAmerica},
+	{"BA", "Bosnia and Herzegovina", continentEurope},
+	{"BW", "Botswana", continentAfrica},
+	{"BR", "Brazil", continentSouthAmerica},
+	{"BN", "Brunei", continentAsia},
+	{"BG", "Bulgaria", continentEurope},
+	{"BF", "Burkina Faso", continentAfrica},
+	{"BI", "Burundi", continentAfrica},
+	{"KH", "Cambodia", continentAsia},
+	{"CM", "Cameroon", continentAfrica},
+	{"CA", "Canada", continentNorthAmerica},
+	{"CV", "Cape Verde", continentAfrica},
+	{"KY", "Cayman Islands", continentNorthAmerica},
+	{"CF", "Central African Republic", continentAfrica},
+	{"TD", "Chad", continentAfrica},
+	{"CL", "Chile", continentSouthAmerica},
+	{"CN", "China", continentAsia},
+	{"CO", "Colombia", continentSouthAmerica},
+	{"KM", "Comoros", continentAfrica},
+	{"CG", "Congo", continentAfrica},
+	{"CD", "Democratic Republic of the Congo", continentAfrica},
+	{"CR", "Costa Rica", continentNorthAmerica},
+	{"CI", "Ivory Coast", continentAfrica},
+	{"HR", "Croatia", continentEurope},
+	{"CU", "Cuba", continentNorthAmerica},
+	{"CW", "Curacao", continentNorthAmerica},
+	{"CY", "Cyprus", continentEurope},
+	{"CZ", "Czechia", continentEurope},
+	{"DK", "Denmark", continentEurope},
+	{"DJ", "Djibouti", continentAfrica},
+	{"DM", "Dominica", continentNorthAmerica},
+	{"DO", "Dominican Republic", continentNorthAmerica},
+	{"EC", "Ecuador", continentSouthAmerica},
+	{"EG", "Egypt", continentAfrica},
+	{"SV", "El Salvador", continentNorthAmerica},
+	{"GQ", "Equatorial Guinea", continentAfrica},
+	{"ER", "Eritrea", continentAfrica},
+	{"EE", "Estonia", continentEurope},
+	{"SZ", "Eswatini", continentAfrica},
+	{"ET", "Ethiopia", continentAfrica},
+	{"FO", "Faroe Islands", continentEurope},
+	{"FJ", "Fiji", continentOceania},
+	{"FI", "Finland", continentEurope},
+	{"FR", "France", continentEurope},
+	{"GF", "French Guiana", continentSouthAmerica},
+	{"PF", "French Polynesia", continentOceania},
+	{"GA", "Gabon", continentAfrica},
+	{"GM", "Gambia", continentAfrica},
+	{"GE", "Georgia", continentAsia},
+	{"DE", "Germany", continentEurope},
+	{"GH", "Ghana", continentAfrica},
+	{"GI", "Gibraltar", continentEurope},
+	{"GR", "Greece", continentEurope},
+	{"GL", "Greenland", continentNorthAmerica},
+	{"GD", "Grenada", continentNorthAmerica},
+	{"GP", "Guadeloupe", continentNorthAmerica},
+	{"GU", "Guam", continentOceania},
+	{"GT", "Guatemala", continentNorthAmerica},
+	{"GG", "Guernsey", continentEurope},
+	{"GN", "Guinea", continentAfrica},
+	{"GW", "Guinea-Bissau", continentAfrica},
+	{"GY", "Guyana", continentSouthAmerica},
+	{"HT", "Haiti", continentNorthAmerica},
+	{"HN", "Honduras", continentNorthAmerica},
+	{"HK", "Hong Kong", continentAsia},
+	{"HU", "Hungary", continentEurope},
+	{"IS", "Iceland", continentEurope},
+	{"IN", "India", continentAsia},
+	{"ID", "Indonesia", continentAsia},
+	{"IR", "Iran", continentAsia},
+	{"IQ", "Iraq", continentAsia},
+	{"IE", "Ireland", continentEurope},
+	{"IM", "Isle of Man", continentEurope},
+	{"IL", "Israel", continentAsia},
+	{"IT", "Italy", continentEurope},
+	{"JM", "Jamaica", continentNorthAmerica},
+	{"JP", "Japan", continentAsia},
+	{"JE", "Jersey", continentEurope},
+	{"JO", "Jordan", continentAsia},
+	{"KZ", "Kazakhstan", continentAsia},
+	{"KE", "Kenya", continentAfrica},
+	{"KI", "Kiribati", continentOceania},
+	{"KP", "North Korea", continentAsia},
+	{"KR", "South Korea", continentAsia},
+	{"XK", "Kosovo", continentEurope},
+	{"KW", "Kuwait", continentAsia},
+	{"KG", "Kyrgyzstan", continentAsia},
+	{"LA", "Laos", continentAsia},
+	{"LV", "Latvia", continentEurope},
+	{"LB", "Lebanon", continentAsia},
+	{"LS", "Lesotho", continentAfrica},
+	{"LR", "Liberia", continentAfrica},
+	{"LY", "Libya", continentAfrica},
+	{"LI", "Liechtenstein", continentEurope},
+	{"LT", "Lithuania", continentEurope},
+	{"LU", "Luxembourg", continentEurope},
+	{"MO", "Macao", continentAsia},
+	{"MG", "Madagascar", continentAfrica},
+	{"MW", "Malawi", continentAfrica},
+	{"MY", "Malaysia", continentAsia},
+	{"MV", "Maldives", continentAsia},
+	{"ML", "Mali", continentAfrica},
+	{"MT", "Malta", continentEurope},
+	{"MH", "Marshall Islands", continentOceania},
+	{"MQ", "Martinique", continentNorthAmerica},
+	{"MR", "Mauritania", continentAfrica},
+	{"MU", "Mauritius", continentAfrica},
+	{"YT", "Mayotte", continentAfrica},
+	{"MX", "Mexico", continentNorthAmerica},
+	{"FM", "Micronesia", continentOceania},
+	{"MD", "Moldova", continentEurope},
+	{"MC", "Monaco", continentEurope},
+	{"MN", "Mongolia", continentAsia},
+	{"ME", "Montenegro", continentEurope},
+	{"MA", "Morocco", continentAfrica},
+	{"MZ", "Mozambique", continentAfrica},
+	{"MM", "Myanmar", continentAsia},
+	{"NA", "Namibia", continentAfrica},
+	{"NR", "Nauru", continentOceania},
+	{"NP", "Nepal", continentAsia},
+	{"NL", "Netherlands", continentEurope},
+	{"NC", "New Caledonia", continentOceania},
+	{"NZ", "New Zealand", continentOceania},
+	{"NI", "Nicaragua", continentNorthAmerica},
+	{"NE", "Niger", continentAfrica},
+	{"NG", "Nigeria", continentAfrica},
+	{"MK", "North Macedonia", continentEurope},
+	{"NO", "Norway", continentEurope},
+	{"OM", "Oman", continentAsia},
+	{"PK", "Pakistan", continentAsia},
+	{"PW", "Palau", continentOceania},
+	{"PS", "Palestine", continentAsia},
+	{"PA", "Panama", continentNorthAmerica},
+	{"PG", "Papua New Guinea", continentOceania},
+	{"PY", "Paraguay", continentSouthAmerica},
+	{"PE", "Peru", continentSouthAmerica},
+	{"PH", "Philippines", continentAsia},
+	{"PL", "Poland", continentEurope},
+	{"PT", "Portugal", continentEurope},
+	{"PR", "Puerto Rico", continentNorthAmerica},
+	{"QA", "Qatar", continentAsia},
+	{"RE", "Reunion", continentAfrica},
+	{"RO", "Romania", continentEurope},
+	{"RU", "Russia", continentEurope},
+	{"RW", "Rwanda", continentAfrica},
+	{"KN", "Saint Kitts and Nevis", continentNorthAmerica},
+	{"LC", "Saint Lucia", continentNorthAmerica},
+	{"VC", "Saint Vincent and the Grenadines", continentNorthAmerica},
+	{"WS", "Samoa", continentOceania},
+	{"SM", "San Marino", continentEurope},
+	{"ST", "Sao Tome and Principe", continentAfrica},
+	{"SA", "Saudi Arabia", continentAsia},
+	{"SN", "Senegal", continentAfrica},
+	{"RS", "Serbia", continentEurope},
+	{"SC", "Seychelles", continentAfrica},
+	{"SL", "Sierra Leone", continentAfrica},
+	{"SG", "Singapore", continentAsia},
+	{"SX", "Sint Maarten", continentNorthAmerica},
+	{"SK", "Slovakia", continentEurope},
+	{"SI", "Slovenia", continentEurope},
+	{"SB", "Solomon Islands", continentOceania},
+	{"SO", "Somalia", continentAfrica},
+	{"ZA", "South Africa", continentAfrica},
+	{"SS", "South Sudan", continentAfrica},
+	{"ES", "Spain", continentEurope},
+	{"LK", "Sri Lanka", continentAsia},
+	{"SD", "Sudan", continentAfrica},
+	{"SR", "Suriname", continentSouthAmerica},
+	{"SE", "Sweden", continentEurope},
+	{"CH", "Switzerland", continentEurope},
+	{"SY", "Syria", continentAsia},
+	{"TW", "Taiwan", continentAsia},
+	{"TJ", "Tajikistan", continentAsia},
+	{"TZ", "Tanzania", continentAfrica},
+	{"TH", "Thailand", continentAsia},
+	{"TL", "Timor-Leste", continentAsia},
+	{"TG", "Togo", continentAfrica},
+	{"TO", "Tonga", continentOceania},
+	{"TT", "Trinidad and Tobago", continentNorthAmerica},
+	{"TN", "Tunisia", continentAfrica},
+	{"TR", "Turkey", continentAsia},
+	{"TM", "Turkmenistan", continentAsia},
+	{"TC", "Turks and Caicos Islands", continentNorthAmerica},
+	{"TV", "Tuvalu", continentOceania},
+	{"UG", "Uganda", continentAfrica},
+	{"UA", "Ukraine", continentEurope},
+	{"AE", "United Arab Emirates", continentAsia},
+	{"GB", "United Kingdom", continentEurope},
+	{"US", "United States", continentNorthAmerica},
+	{"UY", "Uruguay", continentSouthAmerica},
+	{"UZ", "Uzbekistan", continentAsia},
+	{"VU", "Vanuatu", continentOceania},
+	{"VA", "Vatican City", continentEurope},
+	{"VE", "Venezuela", continentSouthAmerica},
+	{"VN", "Vietnam", continentAsia},
+	{"VG", "British Virgin Islands", continentNorthAmerica},
+	{"VI", "U.S. Virgin Islands", continentNorthAmerica},
+	{"EH", "Western Sahara", continentAfrica},
+	{"YE", "Yemen", continentAsia},
+	{"ZM", "Zambia", continentAfrica},
+	{"ZW", "Zimbabwe", continentAfrica},
+}
+
+// countryAliases are other names the nodes or users give to countries
+var countryAliases = map[string]string{
+	"the netherlands":          "NL",
+	"holland":                  "NL",
+	"czech republic":           "CZ",
+	"russian federation":       "RU",
+	"united states of america": "US",
+	"usa":                      "US",
+	"uk":                       "GB",
+	"great britain":            "GB",
+	"england":                  "GB",
+	"republic of korea":        "KR",
+	"korea":                    "KR",
+	"cote d'ivoire":            "CI",
+	"côte d'ivoire":            "CI",
+	"macedonia":                "MK",
+	"swaziland":                "SZ",
+	"viet nam":                 "VN",
+	"burma":                    "MM",
+	"cabo verde":               "CV",
+	"east timor":               "TL",
+	"turkiye":                  "TR",
+	"türkiye":                  "TR",
+	"uae":                      "AE",
+}
+
+// lookupCountry finds a country by name, alias or ISO code, case insensitive
+func lookupCountry(name string) (country, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if code, ok := countryAliases[name]; ok {
+		name = strings.ToLower(code)
+	}
+	for _, c := range countries {
+		if strings.ToLower(c.Name) == name || strings.ToLower(c.Code) == name {
+			return c, true
+		}
+	}
+	return country{}, false
+}
+
+// countryNames returns the sorted names of the countries table
+func countryNames() []string {
+	names := make([]string, 0, len(countries))
+	for _, c := range countries {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseCoordinate parses a latitude or longitude entered by the user, empty means 0
+func parseCoordinate(text string) (float64, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(text, 64)
+}
+
+// normalizeLocation validates l and returns it with the country name of the
+// table and its continent. An empty country is only valid with an empty city
+func normalizeLocation(l Location) (Location, error) {
+	l.City = strings.TrimSpace(l.City)
+	l.Country = strings.TrimSpace(l.Country)
+	l.Continent = strings.TrimSpace(l.Continent)
+
+	if l.Latitude < -90 || l.Latitude > 90 || math.IsNaN(l.Latitude) {
+		return l, fmt.Errorf("latitude should be between -90 and 90")
+	}
+	if l.Longitude < -180 || l.Longitude > 180 || math.IsNaN(l.Longitude) {
+		return l, fmt.Errorf("longitude should be between -180 and 180")
+	}
+
+	if l.Country == "" {
+		if l.City != "" {
+			return l, fmt.Errorf("the country of %s is required", l.City)
+		}
+		return l, nil
+	}
+
+	c, ok := lookupCountry(l.Country)
+	if !ok {
+		return l, fmt.Errorf("unknown country %q", l.Country)
+	}
+	if l.Continent != "" && !strings.EqualFold(l.Continent, c.Continent) {
+		return l, fmt.Errorf("%s is in %s, not in %s", c.Name, c.Continent, l.Continent)
+	}
+	l.Country, l.Continent = c.Name, c.Continent
+	return l, nil
+}
+
+// String returns the city, country and continent of the location
+func (l Location) String() string {
+	parts := make([]string, 0, 3)
+	for _, p := range []string{l.City, l.Country, l.Continent} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) == 0 {
+		return "unknown"
+	}
+	s := strings.Join(parts, ", ")
+	if l.Latitude != 0 || l.Longitude != 0 {
+		s += fmt.Sprintf(" (%.4f, %.4f)", l.Latitude, l.Longitude)
+	}
+	return s
+}
+
+// deriveLocation proposes the location of a farm from the locations its
+// nodes report: the most common city and country, with the average
+// coordinates of the nodes there. The number of nodes in that place is
+// returned with it
+func deriveLocation(nodes []Node) (Location, int, error) {
+	type place struct {
+		city, country string
+	}
+	counts := make(map[place]int)
+	var best place
+	for _, n := range nodes {
+		c, ok := lookupCountry(n.Location.Country)
+		if !ok {
+			continue
+		}
+		p := place{city: strings.TrimSpace(n.Location.City), country: c.Name}
+		counts[p]++
+		// ties are broken by name so the result doesn't depend on the nodes order
+		if counts[p] > counts[best] || (counts[p] == counts[best] && p.country+p.city < best.country+best.city) {
+			best = p
+		}
+	}
+	if counts[best] == 0 {
+		return Location{}, 0, fmt.Errorf("none of the %d nodes reports a known location", len(nodes))
+	}
+
+	l := Location{City: best.city, Country: best.country}
+	located := 0
+	for _, n := range nodes {
+		c, _ := lookupCountry(n.Location.Country)
+		if c.Name != best.country || strings.TrimSpace(n.Location.City) != best.city {
+			continue
+		}
+		if n.Location.Latitude == 0 && n.Location.Longitude == 0 {
+			continue
+		}
+		l.Latitude += n.Location.Latitude
+		l.Longitude += n.Location.Longitude
+		located++
+	}
+	if located > 0 {
+		l.Latitude /= float64(located)
+		l.Longitude /= float64(located)
+	}
+
+	l, err := normalizeLocation(l)
+	return l, counts[best], err
+}
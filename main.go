@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/mail"
 	"os"
 	"path/filepath"
@@ -33,37 +36,94 @@ var (
 	SeedVersion1 = MustParse("1.0.0")
 	// SeedVersion11 (json mnemonic)
 	SeedVersion11 = MustParse("1.1.0")
+	// SeedVersion12 (passphrase encrypted json mnemonic)
+	SeedVersion12 = MustParse("1.2.0")
 	// SeedVersionLatest link to latest seed version
-	SeedVersionLatest     = SeedVersion11
+	SeedVersionLatest     = SeedVersion12
 	threebotId        int = 0
 	userid                = &UserIdentity{}
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+	runGUI()
+}
+
+func runGUI() {
 	var expclient *Client
 
 	myApp := app.New()
 	myWindow := myApp.NewWindow("Go Farmer!!")
-	explorerUrl, _ := explorersUrls["Mainnet"]
+	network, err := networkFromEnv()
+	if err != nil {
+		log.Println(err)
+		network, _ = resolveNetwork("", "")
+	}
+	if store, err := newSecretStore(os.Getenv(secretStoreEnv)); err != nil {
+		log.Println("failed to open secret store, using seed files: ", err)
+	} else {
+		seedStore = store
+	}
+	if err := migrateLegacySeedFile(); err != nil {
+		log.Println(err)
+	}
+
+	// retryStatus shows the explorer requests being retried, requests that
+	// failed after their retries stay shown until dismissed
+	retryStatus := widget.NewLabel("")
+	retryStatus.Wrapping = fyne.TextWrapWord
+	var retryBar *fyne.Container
+	retryBar = container.NewBorder(nil, nil, nil, widget.NewButtonWithIcon("", theme.CancelIcon(), func() {
+		retryBar.Hide()
+	}), retryStatus)
+	retryBar.Hide()
+	retryPolicy := DefaultRetryPolicy
+	retryPolicy.OnRetry = func(s RetryStatus) {
+		if s.Done && s.Err == nil {
+			retryBar.Hide()
+			return
+		}
+		retryStatus.SetText("explorer: " + s.String())
+		retryBar.Show()
+	}
+	// clientOptions reach the explorer through the proxy and CA of the
+	// environment and report retries in retryStatus
+	clientOptions, err := connectionOptions(os.Getenv(proxyEnv), os.Getenv(caCertEnv))
+	if err != nil {
+		log.Println("ignoring the connection settings: ", err)
+	}
+	clientOptions = append(clientOptions, WithRetryPolicy(retryPolicy))
+	// newClient returns an explorer client of the selected network
+	newClient := func(id Identity) (*Client, error) {
+		return NewClient(network.URL, id, clientOptions...)
+	}
 
+	profileNameInput := widget.NewEntry()
+	profileNameInput.Disable()
 	threebotIdInput := widget.NewEntry()
 	threebotIdInput.Disable()
 	threebotNameInput := widget.NewEntry()
 	emailInput := widget.NewEntry()
 	wordsInput := widget.NewMultiLineEntry()
+	passphraseInput := widget.NewPasswordEntry()
 	infoIdentityLabel := widget.NewLabel("")
 	errorsIdentityLabel := widget.NewLabel("")
 
 	farmNameInput := widget.NewEntry()
 	tftAddressInput := widget.NewEntry()
+	tftAddressInput.Validator = validateStellarAddress
 	errorsFarmLabel := widget.NewLabel("")
 	infoFarmLabel := widget.NewLabel("")
 
 	farmOwnerIdEntry := widget.NewEntry()
+	farmOwnerIdEntry.Disable()
 	farmIdEntryUpdate := widget.NewEntry()
 	farmIdEntryUpdate.Disable()
 	farmNameInputUpdate := widget.NewEntry()
 	tftAddressInputUpdate := widget.NewEntry()
+	tftAddressInputUpdate.Validator = validateStellarAddress
 	errorsFarmLabelUpdate := widget.NewLabel("")
 	infoFarmLabelUpdate := widget.NewLabel("")
 
@@ -86,67 +146,163 @@ func main() {
 	nodesNames := make([]string, 0)
 	nodesBinding := binding.BindStringList(&nodesNames)
 
-	seedpath, err := getSeedPath()
-	fmt.Println(seedpath)
-	if err != nil {
-		println(err)
-		os.Exit(1)
-	}
-	if _, err = os.Stat(seedpath); !os.IsNotExist(err) {
-		userid.Load(seedpath)
-		threebotId = int(userid.ThreebotID)
-		threebotIdInput.SetText(fmt.Sprintf("%d", threebotId))
-		if expclient, err = NewClient(explorerUrl, userid); err == nil {
-			if u, err := expclient.Phonebook.Get(userid.ThreebotID); err == nil {
-				wordsInput.Text = userid.Mnemonic
-				emailInput.Text = u.Email
-				threebotNameInput.Text = u.Name
-			} else {
+	profilesData := make([]Profile, 0)
+	profilesNames := make([]string, 0)
+	profilesBinding := binding.BindStringList(&profilesNames)
+
+	refreshProfiles := func() {
+		profiles, err := listProfiles(network)
+		if err != nil {
+			log.Println("failed to list profiles: ", err)
+		}
+		profilesData, profilesNames = profiles, make([]string, 0, len(profiles))
+		for _, p := range profiles {
+			profilesNames = append(profilesNames, p.String())
+		}
+		profilesBinding.Set(profilesNames)
+	}
 
-				fmt.Println("failed to get explorer client: ", err)
+	var seedpath string
+	var showConvertDialog func(src string)
+
+	// reloadFarms lists the farms of the identity in the background, then is
+	// called once they are shown
+	reloadFarms := func(then func()) {
+		client, tid := expclient, int64(threebotId)
+		var farms []Farm
+		var names []string
+		runCancellable("Farms", "loading your farms from the explorer", myWindow, func(ctx context.Context) (err error) {
+			farms, names, err = ListAllFarmsAndNamesContext(ctx, client, tid)
+			return errors.Wrap(err, "failed to list farms")
+		}, func(err error) {
+			if err != nil {
+				dialog.ShowError(err, myWindow)
+				return
+			}
+			farmsListData, farmsNames = farms, names
+			farmsBinding.Set(farmsNames)
+			if then != nil {
+				then()
 			}
+		})
+	}
 
+	// loadIdentity (re)loads the identity and its farms against the selected network
+	loadIdentity := func() {
+		var err error
+		seedpath, err = getSeedPath(network)
+		if err != nil {
+			dialog.ShowError(err, myWindow)
+			return
 		}
 
+		if profile, err := getActiveProfile(network); err == nil {
+			profileNameInput.SetText(profile)
+		}
+		refreshProfiles()
+
+		expclient = nil
+		threebotId = 0
+		userid = &UserIdentity{}
+		threebotIdInput.SetText("")
+		threebotNameInput.SetText("")
+		emailInput.SetText("")
+		wordsInput.SetText("")
+		farmsListData, farmsNames = make([]Farm, 0), make([]string, 0)
+		nodesListData, nodesNames = make([]Node, 0), make([]string, 0)
+
+		farmsBinding.Set(farmsNames)
+		nodesBinding.Set(nodesNames)
+
+		if !seedExists(seedpath) {
+			return
+		}
+
+		// identityLoaded connects to the explorer once the seed file is read
+		identityLoaded := func() {
+			threebotId = int(userid.ThreebotID)
+			threebotIdInput.SetText(fmt.Sprintf("%d", threebotId))
+			if expclient, err = newClient(userid); err == nil {
+				if u, err := expclient.Phonebook.Get(userid.ThreebotID); err == nil {
+					wordsInput.SetText(userid.Mnemonic)
+					emailInput.SetText(u.Email)
+					threebotNameInput.SetText(u.Name)
+				} else {
+
+					fmt.Println("failed to get explorer client: ", err)
+				}
+				reloadFarms(nil)
+			}
+		}
+
+		if err := userid.Load(seedpath); err == ErrPassphraseRequired {
+			path := seedpath
+			var unlock func()
+			unlock = func() {
+				showPassphraseDialog(fmt.Sprintf("Unlock %s", filepath.Base(path)), false, myWindow, func(passphrase string) {
+					if err := userid.LoadWithPassphrase(path, passphrase); err != nil {
+						dialog.ShowError(err, myWindow)
+						unlock()
+						return
+					}
+					identityLoaded()
+				})
+			}
+			unlock()
+			return
+		} else if err == ErrLegacySeed {
+			path := seedpath
+			dialog.ShowConfirm("Legacy seed file", fmt.Sprintf("%s uses the old %s format, do you want to convert it now? A backup of it is kept.", path, SeedVersion1), func(b bool) {
+				if b {
+					showConvertDialog(path)
+				}
+			}, myWindow)
+			return
+		} else if err != nil {
+			log.Println("failed to load identity: ", err)
+		}
+		identityLoaded()
 	}
+	loadIdentity()
 
 	var farmToEditIdx int64 = 0
 
 	formIdentity := &widget.Form{
 		Items: []*widget.FormItem{ // we can specify items in the constructor
+			{Text: "Profile", Widget: profileNameInput, HintText: "switch profiles from the Profiles tab"},
 			{Text: "3Bot ID", Widget: threebotIdInput, HintText: "3Bot ID"},
 			{Text: "3Bot Name", Widget: threebotNameInput, HintText: "should end with .3bot"},
 			{Text: "Email", Widget: emailInput},
 			{Text: "Words", Widget: wordsInput, HintText: "leave empty to generate"},
+			{Text: "Passphrase", Widget: passphraseInput, HintText: "optional, encrypts the seed file"},
 			{Widget: infoIdentityLabel},
 			{Widget: errorsIdentityLabel},
 		},
 		SubmitText: "Register your identity",
 		OnSubmit: func() { // optional, handle form submission
-			log.Println(threebotNameInput.Text, emailInput.Text, farmNameInput.Text, wordsInput.Text, tftAddressInput.Text)
+			log.Println(threebotNameInput.Text, emailInput.Text, farmNameInput.Text, tftAddressInput.Text)
 			errs := validateIdentityData(threebotNameInput.Text, emailInput.Text, wordsInput.Text)
 			errorsIdentityLabel.Text = strings.Join(errs, "\n")
 			if len(errs) == 0 {
-				seedpath, err := getSeedPath()
+				seedpath, err := getSeedPath(network)
 				if err != nil {
-					println(err)
-					os.Exit(1)
+					dialog.ShowError(err, myWindow)
+					return
 				}
 				doGen := func() {
-					_, ui, err := generateID(explorerUrl, threebotNameInput.Text, emailInput.Text, seedpath, wordsInput.Text)
+					_, ui, err := generateID(network.URL, threebotNameInput.Text, emailInput.Text, seedpath, wordsInput.Text, passphraseInput.Text, clientOptions...)
 					if err != nil {
-						fmt.Println(err)
-						fmt.Println(ui)
 						errorsIdentityLabel.Text = fmt.Sprintf("Error while generating identity %s", err)
 						dialog.ShowError(fmt.Errorf(errorsIdentityLabel.Text), myWindow)
 
 					} else {
 						infoIdentityLabel.Text = fmt.Sprintf("your 3Bot ID is %d: and seed is saved at %s", ui.ThreebotID, seedpath)
-						fmt.Println("menoms: ", ui.Mnemonic)
 						wordsInput.SetText(ui.Mnemonic)
-						dialog.ShowInformation("Success", infoIdentityLabel.Text, myWindow)
+						refreshProfiles()
+						dialog.ShowInformation("Success", infoIdentityLabel.Text+"\nback it up with the Export identity or Paper backup buttons", myWindow)
 						threebotId = int(ui.ThreebotID)
-						expclient, err = NewClient(explorerUrl, ui)
+						userid = ui
+						expclient, err = newClient(ui)
 						if err != nil {
 							fmt.Println("failed to get explorer client: ", err)
 							dialog.ShowError(fmt.Errorf("failed to get explorer client"), myWindow)
@@ -154,7 +310,7 @@ func main() {
 					}
 				}
 				errorsIdentityLabel.Text = ""
-				if _, err = os.Stat(seedpath); !os.IsNotExist(err) {
+				if seedExists(seedpath) {
 					dialog.ShowConfirm("Overwriting your 3Bot Identity", "Are you sure you want to  overwrite the existing identity? Make sure to backup your seed file.?\n\n", func(b bool) {
 						if b {
 
@@ -175,25 +331,69 @@ func main() {
 		},
 	}
 
+	encryptSeedButton := widget.NewButton("Encrypt seed file", func() {
+		if userid.Key().PrivateKey == nil {
+			dialog.ShowInformation("Encrypt seed file", "please register or load an identity first", myWindow)
+			return
+		}
+		showPassphraseDialog("Encrypt seed file", true, myWindow, func(passphrase string) {
+			if err := userid.SaveWithPassphrase(seedpath, passphrase); err != nil {
+				dialog.ShowError(errors.Wrap(err, "failed to encrypt seed file"), myWindow)
+				return
+			}
+			refreshProfiles()
+			dialog.ShowInformation("Seed file encrypted", fmt.Sprintf("%s is encrypted, you will be asked for the passphrase when it's loaded", seedpath), myWindow)
+		})
+	})
+
+	convertSeedButton := widget.NewButton("Convert legacy seed file", func() {
+		dialog.ShowFileOpen(func(r fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, myWindow)
+				return
+			}
+			if r == nil {
+				return
+			}
+			src := r.URI().Path()
+			r.Close()
+
+			if src != seedpath && seedExists(seedpath) {
+				dialog.ShowConfirm("Overwriting your 3Bot Identity", fmt.Sprintf("Profile %s already has an identity, are you sure you want to overwrite it? Make sure to backup your seed file.", profileNameInput.Text), func(b bool) {
+					if b {
+						showConvertDialog(src)
+					}
+				}, myWindow)
+				return
+			}
+			showConvertDialog(src)
+		}, myWindow)
+	})
+
+	registerLocation := newLocationEditor()
 	formFarm := &widget.Form{
-		Items: []*widget.FormItem{ // we can specify items in the constructor
+		Items: append(append([]*widget.FormItem{ // we can specify items in the constructor
 			{Text: "Farm Name", Widget: farmNameInput},
-			{Text: "TFT Address", Widget: tftAddressInput, HintText: "valid TFT address (56 characters)"},
-			{Widget: infoFarmLabel},
-			{Widget: errorsFarmLabel},
-		},
+			{Text: "TFT Address", Widget: tftAddressInput, HintText: "stellar address starting with G"},
+		}, registerLocation.FormItems()...),
+			&widget.FormItem{Widget: infoFarmLabel},
+			&widget.FormItem{Widget: errorsFarmLabel},
+		),
 		SubmitText: "Register your farm",
 		OnSubmit: func() { // optional, handle form submission
-			log.Println(threebotNameInput.Text, emailInput.Text, farmNameInput.Text, wordsInput.Text, tftAddressInput.Text)
+			log.Println(threebotNameInput.Text, emailInput.Text, farmNameInput.Text, tftAddressInput.Text)
 			errs := validateData(threebotNameInput.Text, emailInput.Text, farmNameInput.Text, tftAddressInput.Text)
+			location, err := registerLocation.Location()
+			if err != nil {
+				errs = append(errs, err.Error())
+			}
 			errorsFarmLabel.Text = strings.Join(errs, "\n")
 			if len(errs) == 0 && threebotId > 0 {
-				if farm, err := registerFarm(expclient, farmNameInput.Text, emailInput.Text, tftAddressInput.Text, threebotId); err == nil {
+				if farm, err := registerFarm(expclient, farmNameInput.Text, emailInput.Text, tftAddressInput.Text, nil, location, threebotId); err == nil {
 
 					infoFarmLabel.Text = fmt.Sprintf("farm with ID %d is created", farm.ID)
 					dialog.ShowInformation("Farm Registered!", infoFarmLabel.Text, myWindow)
-					farmsListData, farmsNames = ListAllFarmsAndNames(expclient, int64(threebotId))
-					farmsBinding.Set(farmsNames)
+					reloadFarms(nil)
 				} else {
 					errorsFarmLabel.Text = fmt.Sprintf("Error while registering farm %s", err)
 					dialog.ShowError(fmt.Errorf(errorsFarmLabel.Text), myWindow)
@@ -206,46 +406,579 @@ func main() {
 		},
 	}
 
+	// confirmFarmUpdate shows the changes edit makes to the edited farm and
+	// submits them once confirmed
+	var confirmFarmUpdate func(edit func(farm *Farm))
+	var farmEditTabs *container.AppTabs
+
 	formFarmUpdate := &widget.Form{
 		Items: []*widget.FormItem{ // we can specify items in the constructor
-			{Text: "Owner ID", Widget: farmOwnerIdEntry, HintText: "Change to transfer farm ownership"},
+			{Text: "Owner ID", Widget: farmOwnerIdEntry, HintText: "Use Transfer ownership to change it"},
 			{Text: "Farm ID", Widget: farmIdEntryUpdate},
 			{Text: "Farm Name", Widget: farmNameInputUpdate},
-			{Text: "TFT Address", Widget: tftAddressInputUpdate, HintText: "valid TFT address (56 characters)"},
+			{Text: "TFT Address", Widget: tftAddressInputUpdate, HintText: "stellar address starting with G"},
 			{Widget: infoFarmLabelUpdate},
 			{Widget: errorsFarmLabelUpdate},
 		},
 		SubmitText: "Edit your farm",
 		OnSubmit: func() { // optional, handle form submission
-			log.Println(threebotNameInput.Text, emailInput.Text, farmNameInputUpdate.Text, wordsInput.Text, tftAddressInputUpdate.Text)
+			log.Println(threebotNameInput.Text, emailInput.Text, farmNameInputUpdate.Text, tftAddressInputUpdate.Text)
 			errs := validateData(threebotNameInput.Text, emailInput.Text, farmNameInputUpdate.Text, tftAddressInputUpdate.Text)
 			errorsFarmLabelUpdate.Text = strings.Join(errs, "\n")
 			if len(errs) == 0 && threebotId > 0 {
-				farmOwnerIDAsInt, err := strconv.Atoi(farmOwnerIdEntry.Text)
-				if err != nil {
-					errorsFarmLabelUpdate.Text = fmt.Sprintf("Error while updating farm %s", err)
-					dialog.ShowError(fmt.Errorf(errorsFarmLabelUpdate.Text), myWindow)
-				}
-				if farm, err := updateFarm(expclient, farmsListData[farmToEditIdx].ID, int64(farmOwnerIDAsInt), farmNameInputUpdate.Text, emailInput.Text, tftAddressInputUpdate.Text, threebotId); err == nil {
-
-					infoFarmLabelUpdate.Text = fmt.Sprintf("farm with ID %d is updated", farm.ID)
-					dialog.ShowInformation("Farm updated!", infoFarmLabelUpdate.Text, myWindow)
-					farmsListData, farmsNames = ListAllFarmsAndNames(expclient, int64(threebotId))
-					farmsBinding.Set(farmsNames)
-				} else {
-					errorsFarmLabelUpdate.Text = fmt.Sprintf("Error while updating farm %s", err)
-					dialog.ShowError(fmt.Errorf(errorsFarmLabelUpdate.Text), myWindow)
-				}
+				confirmFarmUpdate(func(farm *Farm) {
+					setFarmDetails(farm, farmNameInputUpdate.Text, "", tftAddressInputUpdate.Text)
+				})
 				log.Println(errs)
 			}
 		},
 	}
 
-	if expclient != nil {
-		farmsListData, farmsNames = ListAllFarmsAndNames(expclient, int64(threebotId))
-		farmsBinding.Set(farmsNames)
+	farmLocation := newLocationEditor()
+
+	// showFarmDetails fills the farm form with farm
+	showFarmDetails := func(farm Farm) {
+		farmNameInputUpdate.SetText(farm.Name)
+		tftAddressInputUpdate.SetText(farmTFTAddress(farm))
+		farmOwnerIdEntry.SetText(fmt.Sprintf("%d", farm.ThreebotID))
+		farmIdEntryUpdate.SetText(fmt.Sprintf("%d", farm.ID))
+	}
+
+	editedWallets := make([]WalletAddress, 0)
+	walletsNames := make([]string, 0)
+	walletsBinding := binding.BindStringList(&walletsNames)
+	selectedWallet := -1
+
+	walletsList := widget.NewListWithData(walletsBinding,
+		func() fyne.CanvasObject {
+			return widget.NewLabel("template")
+		},
+		func(i binding.DataItem, o fyne.CanvasObject) {
+			o.(*widget.Label).Bind(i.(binding.String))
+		})
+	walletsList.OnSelected = func(id widget.ListItemID) {
+		selectedWallet = id
+	}
+
+	// setEditedWallets shows the wallets being edited, they are saved with the Save button
+	setEditedWallets := func(wallets []WalletAddress) {
+		if selectedWallet >= 0 {
+			walletsList.Unselect(selectedWallet)
+		}
+		selectedWallet = -1
+		editedWallets, walletsNames = wallets, make([]string, 0, len(wallets))
+		for _, w := range wallets {
+			walletsNames = append(walletsNames, w.String())
+		}
+		walletsBinding.Set(walletsNames)
+	}
+
+	// showWalletDialog edits w (empty to add a wallet) in the edited wallets
+	showWalletDialog := func(w WalletAddress) {
+		assetEntry := widget.NewSelectEntry(walletAssets)
+		assetEntry.SetText(w.Asset)
+		assetEntry.SetPlaceHolder("TFT, FreeTFT, TFTA or any asset code")
+		addressEntry := widget.NewEntry()
+		addressEntry.SetText(w.Address)
+		addressEntry.SetPlaceHolder("stellar address starting with G")
+		addressEntry.Validator = validateStellarAddress
+		dialog.ShowForm("Wallet", "OK", "Cancel", []*widget.FormItem{
+			{Text: "Asset", Widget: assetEntry},
+			{Text: "Address", Widget: addressEntry},
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			edited := WalletAddress{Asset: strings.TrimSpace(assetEntry.Text), Address: strings.TrimSpace(addressEntry.Text)}
+			if err := validateWalletAddress(edited); err != nil {
+				dialog.ShowError(err, myWindow)
+				return
+			}
+			wallets := editedWallets
+			if w.Asset != "" && w.Asset != edited.Asset {
+				wallets = removeWalletAddress(wallets, w.Asset)
+			}
+			setEditedWallets(setWalletAddress(wallets, edited))
+		}, myWindow)
+	}
+
+	farmWalletsButtons := fyne.NewContainerWithLayout(layout.NewGridLayout(4),
+		widget.NewButton("Add", func() {
+			showWalletDialog(WalletAddress{})
+		}),
+		widget.NewButton("Edit", func() {
+			if selectedWallet >= 0 && selectedWallet < len(editedWallets) {
+				showWalletDialog(editedWallets[selectedWallet])
+			}
+		}),
+		widget.NewButton("Remove", func() {
+			if selectedWallet >= 0 && selectedWallet < len(editedWallets) {
+				setEditedWallets(removeWalletAddress(editedWallets, editedWallets[selectedWallet].Asset))
+			}
+		}),
+		widget.NewButton("Save", func() {
+			if err := validateWalletAddresses(editedWallets); err != nil {
+				dialog.ShowError(err, myWindow)
+				return
+			}
+			wallets := append([]WalletAddress{}, editedWallets...)
+			confirmFarmUpdate(func(farm *Farm) {
+				farm.WalletAddresses = wallets
+			})
+		}),
+	)
+	farmWalletsPanel := container.NewBorder(nil, farmWalletsButtons, nil, nil, walletsList)
+
+	farmIPs := make([]PublicIP, 0)
+	farmIPsNames := make([]string, 0)
+	farmIPsBinding := binding.BindStringList(&farmIPsNames)
+	selectedFarmIP := -1
+
+	// editedFarm returns the farm selected in the farms list
+	editedFarm := func() (Farm, bool) {
+		if farmToEditIdx < 0 || int(farmToEditIdx) >= len(farmsListData) {
+			return Farm{}, false
+		}
+		return farmsListData[farmToEditIdx], true
+	}
+
+	farmIPsList := widget.NewListWithData(farmIPsBinding,
+		func() fyne.CanvasObject {
+			return widget.NewLabel("template")
+		},
+		func(i binding.DataItem, o fyne.CanvasObject) {
+			o.(*widget.Label).Bind(i.(binding.String))
+		})
+	removeIPButton := widget.NewButton("Remove", nil)
+
+	// showFarmIPs lists the public ips of farm
+	showFarmIPs := func(farm Farm) {
+		if selectedFarmIP >= 0 {
+			farmIPsList.Unselect(selectedFarmIP)
+		}
+		selectedFarmIP = -1
+		removeIPButton.Disable()
+		farmIPs, farmIPsNames = farm.IPAddresses, make([]string, 0, len(farm.IPAddresses))
+		for _, ip := range farmIPs {
+			farmIPsNames = append(farmIPsNames, ip.String())
+		}
+		farmIPsBinding.Set(farmIPsNames)
 	}
 
+	customPricingCheck := widget.NewCheck("Enable custom pricing", nil)
+	grid3Check := widget.NewCheck("Grid3 compliant pricing", nil)
+	cuPriceInput := widget.NewEntry()
+	suPriceInput := widget.NewEntry()
+	nuPriceInput := widget.NewEntry()
+	ipv4uPriceInput := widget.NewEntry()
+	cruPriceInput := widget.NewEntry()
+	mruPriceInput := widget.NewEntry()
+	hruPriceInput := widget.NewEntry()
+	sruPriceInput := widget.NewEntry()
+	nruPriceInput := widget.NewEntry()
+	earningsLabel := widget.NewLabel("")
+	earningsLabel.Wrapping = fyne.TextWrapWord
+
+	showResourcePrices := func(price NodeResourcePrice) {
+		cruPriceInput.SetText(formatPrice(price.Cru))
+		mruPriceInput.SetText(formatPrice(price.Mru))
+		hruPriceInput.SetText(formatPrice(price.Hru))
+		sruPriceInput.SetText(formatPrice(price.Sru))
+		nruPriceInput.SetText(formatPrice(price.Nru))
+	}
+	currencySelect := widget.NewSelect(priceCurrencyNames(), func(name string) {
+		farm, ok := editedFarm()
+		currency, err := parsePriceCurrency(name)
+		if !ok || err != nil {
+			return
+		}
+		showResourcePrices(resourcePrice(farm, currency))
+	})
+
+	// showFarmPricing fills the pricing editor with the pricing of farm
+	showFarmPricing := func(farm Farm) {
+		pricing := pricingOf(farm)
+		customPricingCheck.SetChecked(pricing.EnableCustomPricing)
+		grid3Check.SetChecked(pricing.IsGrid3Compliant)
+		currencySelect.SetSelected(pricing.CloudUnits.Currency.String())
+		cuPriceInput.SetText(formatPrice(pricing.CloudUnits.CU))
+		suPriceInput.SetText(formatPrice(pricing.CloudUnits.SU))
+		nuPriceInput.SetText(formatPrice(pricing.CloudUnits.NU))
+		ipv4uPriceInput.SetText(formatPrice(pricing.CloudUnits.IPv4U))
+		showResourcePrices(pricing.Resources)
+		earningsLabel.SetText("")
+	}
+
+	// editedPricing reads the pricing from the editor
+	editedPricing := func() (farmPricing, error) {
+		currency, err := parsePriceCurrency(currencySelect.Selected)
+		if err != nil {
+			return farmPricing{}, err
+		}
+		pricing := farmPricing{
+			EnableCustomPricing: customPricingCheck.Checked,
+			IsGrid3Compliant:    grid3Check.Checked,
+			CloudUnits:          NodeCloudUnitPrice{Currency: currency},
+			Resources:           NodeResourcePrice{Currency: currency},
+		}
+		prices := []struct {
+			name  string
+			input *widget.Entry
+			value *float64
+		}{
+			{"CU", cuPriceInput, &pricing.CloudUnits.CU},
+			{"SU", suPriceInput, &pricing.CloudUnits.SU},
+			{"NU", nuPriceInput, &pricing.CloudUnits.NU},
+			{"IPv4U", ipv4uPriceInput, &pricing.CloudUnits.IPv4U},
+			{"CRU", cruPriceInput, &pricing.Resources.Cru},
+			{"MRU", mruPriceInput, &pricing.Resources.Mru},
+			{"HRU", hruPriceInput, &pricing.Resources.Hru},
+			{"SRU", sruPriceInput, &pricing.Resources.Sru},
+			{"NRU", nruPriceInput, &pricing.Resources.Nru},
+		}
+		for _, p := range prices {
+			if *p.value, err = parsePrice(p.input.Text); err != nil {
+				return pricing, fmt.Errorf("invalid %s price: %w", p.name, err)
+			}
+		}
+		return pricing, pricing.Validate()
+	}
+
+	// farmChanged keeps the farm reloaded from the explorer after a change
+	farmChanged := func(farm Farm, err error) {
+		if current, ok := editedFarm(); ok && farm.ID == current.ID {
+			farmsListData[farmToEditIdx] = farm
+			farmsNames[farmToEditIdx] = farm.Name
+			farmsBinding.Set(farmsNames)
+			showFarmDetails(farm)
+			setEditedWallets(farm.WalletAddresses)
+			showFarmIPs(farm)
+			showFarmPricing(farm)
+			farmLocation.Set(farm.Location)
+		}
+		if err != nil {
+			dialog.ShowError(err, myWindow)
+		}
+	}
+
+	// farmsReloaded reloads the farms list after updated changed, the farm
+	// leaves the list if it was transferred to another owner
+	farmsReloaded := func(updated Farm) {
+		reloadFarms(func() {
+			for i, f := range farmsListData {
+				if f.ID == updated.ID {
+					farmToEditIdx = int64(i)
+					farmChanged(updated, nil)
+					return
+				}
+			}
+			farmEditTabs.Hide()
+		})
+	}
+
+	confirmFarmUpdate = func(edit func(farm *Farm)) {
+		farm, ok := editedFarm()
+		if !ok {
+			return
+		}
+		update, current, err := newFarmUpdate(expclient, farm, edit)
+		if err != nil {
+			if errors.Cause(err) == ErrFarmConflict {
+				// show what is on the explorer now so the edit can be redone on it
+				farmChanged(current, err)
+				return
+			}
+			dialog.ShowError(err, myWindow)
+			return
+		}
+		if len(update.Changes) == 0 {
+			dialog.ShowInformation("Farm update", "nothing to update", myWindow)
+			return
+		}
+
+		dialog.ShowConfirm("Updating farm", fmt.Sprintf("The following fields of farm %s will be updated:\n\n%s", farm.Name, formatFarmChanges(update.Changes)), func(b bool) {
+			if !b {
+				return
+			}
+			updated, err := update.Submit(expclient)
+			if err != nil {
+				farmChanged(updated, err)
+				return
+			}
+			infoFarmLabelUpdate.SetText(fmt.Sprintf("farm with ID %d is updated", updated.ID))
+			dialog.ShowInformation("Farm updated!", infoFarmLabelUpdate.Text, myWindow)
+			farmsReloaded(updated)
+		}, myWindow)
+	}
+
+	// showTransferDialog resolves the new owner of the edited farm, and
+	// transfers it once its name is typed to confirm
+	showTransferDialog := func() {
+		farm, ok := editedFarm()
+		if !ok {
+			return
+		}
+		toEntry := widget.NewEntry()
+		toEntry.SetPlaceHolder("3Bot ID of the new owner")
+		dialog.ShowForm("Transfer farm ownership", "Next", "Cancel", []*widget.FormItem{
+			{Text: "New owner", Widget: toEntry},
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			to, err := strconv.ParseInt(strings.TrimSpace(toEntry.Text), 10, 64)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("invalid 3Bot ID %q", toEntry.Text), myWindow)
+				return
+			}
+			transfer, err := newFarmTransfer(expclient, network, userid, farm, to)
+			if err != nil {
+				dialog.ShowError(err, myWindow)
+				return
+			}
+
+			confirmEntry := widget.NewEntry()
+			confirmEntry.SetPlaceHolder(farm.Name)
+			warning := widget.NewLabel(fmt.Sprintf("Farm %s (%d) will be owned by %s (%s, 3Bot %d),\nyou won't be able to manage it anymore.", farm.Name, farm.ID, transfer.ToName, transfer.ToEmail, transfer.To))
+			dialog.ShowForm("Confirm farm transfer", "Transfer", "Cancel", []*widget.FormItem{
+				{Widget: warning},
+				{Text: "Farm name", Widget: confirmEntry, HintText: "type the farm name to confirm"},
+			}, func(ok bool) {
+				if !ok {
+					if err := transfer.Decline(network, "cancelled"); err != nil {
+						dialog.ShowError(err, myWindow)
+					}
+					return
+				}
+				if err := checkTransferConfirmation(transfer, confirmEntry.Text); err != nil {
+					if logErr := transfer.Decline(network, err.Error()); logErr != nil {
+						log.Println(logErr)
+					}
+					dialog.ShowError(err, myWindow)
+					return
+				}
+				updated, err := transfer.Submit(expclient, network, farm)
+				if err != nil {
+					farmChanged(updated, err)
+					return
+				}
+				dialog.ShowInformation("Farm transferred!", fmt.Sprintf("farm %s is now owned by %s", farm.Name, transfer.ToName), myWindow)
+				farmsReloaded(updated)
+			}, myWindow)
+		}, myWindow)
+	}
+
+	showTransferLog := func() {
+		transfers, err := readTransferLog(network)
+		if err != nil {
+			dialog.ShowError(errors.Wrap(err, "failed to read the transfer log"), myWindow)
+			return
+		}
+		lines := make([]string, 0, len(transfers))
+		for _, t := range transfers {
+			lines = append(lines, t.String())
+		}
+		if len(lines) == 0 {
+			lines = append(lines, "no farm was transferred on this network")
+		}
+		dialog.ShowInformation("Farm transfers", strings.Join(lines, "\n"), myWindow)
+	}
+
+	farmIPsList.OnSelected = func(id widget.ListItemID) {
+		selectedFarmIP = id
+		if id < len(farmIPs) && !farmIPs[id].Reserved() {
+			removeIPButton.Enable()
+		} else {
+			removeIPButton.Disable()
+		}
+	}
+	removeIPButton.OnTapped = func() {
+		farm, ok := editedFarm()
+		if !ok || selectedFarmIP < 0 || selectedFarmIP >= len(farmIPs) {
+			return
+		}
+		ip := farmIPs[selectedFarmIP]
+		dialog.ShowConfirm("Removing public IP", fmt.Sprintf("Are you sure you want to remove %s from farm %s?", ip.Address, farm.Name), func(b bool) {
+			if b {
+				farmChanged(deleteFarmIP(expclient, farm.ID, ip.Address))
+			}
+		}, myWindow)
+	}
+	removeIPButton.Disable()
+
+	farmIPsButtons := fyne.NewContainerWithLayout(layout.NewGridLayout(3),
+		widget.NewButton("Add", func() {
+			farm, ok := editedFarm()
+			if !ok {
+				return
+			}
+			addressEntry := widget.NewEntry()
+			addressEntry.SetPlaceHolder("185.69.166.10/24")
+			gatewayEntry := widget.NewEntry()
+			gatewayEntry.SetPlaceHolder("185.69.166.1")
+			dialog.ShowForm("Add public IP", "Add", "Cancel", []*widget.FormItem{
+				{Text: "Address", Widget: addressEntry, HintText: "ip in CIDR notation"},
+				{Text: "Gateway", Widget: gatewayEntry},
+			}, func(ok bool) {
+				if !ok {
+					return
+				}
+				ip := PublicIP{Address: strings.TrimSpace(addressEntry.Text), Gateway: strings.TrimSpace(gatewayEntry.Text)}
+				if err := validatePublicIP(ip.Address, ip.Gateway); err != nil {
+					dialog.ShowError(err, myWindow)
+					return
+				}
+				farmChanged(addFarmIPs(expclient, farm.ID, []PublicIP{ip}))
+			}, myWindow)
+		}),
+		widget.NewButton("Add range", func() {
+			farm, ok := editedFarm()
+			if !ok {
+				return
+			}
+			firstEntry := widget.NewEntry()
+			firstEntry.SetPlaceHolder("185.69.166.10/24")
+			lastEntry := widget.NewEntry()
+			lastEntry.SetPlaceHolder("185.69.166.20")
+			gatewayEntry := widget.NewEntry()
+			gatewayEntry.SetPlaceHolder("185.69.166.1")
+			dialog.ShowForm("Add public IP range", "Add", "Cancel", []*widget.FormItem{
+				{Text: "First address", Widget: firstEntry, HintText: "ip in CIDR notation"},
+				{Text: "Last address", Widget: lastEntry},
+				{Text: "Gateway", Widget: gatewayEntry},
+			}, func(ok bool) {
+				if !ok {
+					return
+				}
+				addresses, err := expandIPRange(firstEntry.Text, lastEntry.Text)
+				if err != nil {
+					dialog.ShowError(err, myWindow)
+					return
+				}
+				ips := make([]PublicIP, 0, len(addresses))
+				for _, address := range addresses {
+					ips = append(ips, PublicIP{Address: address, Gateway: strings.TrimSpace(gatewayEntry.Text)})
+				}
+				dialog.ShowConfirm("Adding public IPs", fmt.Sprintf("Add %d addresses from %s to %s to farm %s?", len(ips), addresses[0], addresses[len(addresses)-1], farm.Name), func(b bool) {
+					if b {
+						farmChanged(addFarmIPs(expclient, farm.ID, ips))
+					}
+				}, myWindow)
+			}, myWindow)
+		}),
+		removeIPButton,
+	)
+	farmIPsPanel := container.NewBorder(nil, farmIPsButtons, nil, nil, farmIPsList)
+
+	previewEarningsButton := widget.NewButton("Preview monthly earnings", func() {
+		pricing, err := editedPricing()
+		if err != nil {
+			dialog.ShowError(err, myWindow)
+			return
+		}
+		if len(nodesListData) == 0 {
+			earningsLabel.SetText("the farm has no nodes yet")
+			return
+		}
+		lines := make([]string, 0, len(nodesListData)+1)
+		var total nodeEarnings
+		for _, node := range nodesListData {
+			e := estimateEarnings(node, pricing)
+			total.CloudUnits += e.CloudUnits
+			total.Resources += e.Resources
+			lines = append(lines, e.String())
+		}
+		lines = append(lines, fmt.Sprintf("total: %.2f %s/month by cloud units, %.2f %s/month by resources, network and public IPs not included",
+			total.CloudUnits, pricing.CloudUnits.Currency, total.Resources, pricing.CloudUnits.Currency))
+		earningsLabel.SetText(strings.Join(lines, "\n"))
+	})
+	savePricingButton := widget.NewButton("Save pricing", func() {
+		pricing, err := editedPricing()
+		if err != nil {
+			dialog.ShowError(err, myWindow)
+			return
+		}
+		confirmFarmUpdate(pricing.apply)
+	})
+	farmPricingPanel := container.NewVScroll(container.NewVBox(
+		customPricingCheck,
+		grid3Check,
+		widget.NewForm(
+			widget.NewFormItem("Currency", currencySelect),
+			widget.NewFormItem("CU price", cuPriceInput),
+			widget.NewFormItem("SU price", suPriceInput),
+			widget.NewFormItem("NU price", nuPriceInput),
+			widget.NewFormItem("IPv4U price", ipv4uPriceInput),
+			widget.NewFormItem("CRU price", cruPriceInput),
+			widget.NewFormItem("MRU price", mruPriceInput),
+			widget.NewFormItem("HRU price", hruPriceInput),
+			widget.NewFormItem("SRU price", sruPriceInput),
+			widget.NewFormItem("NRU price", nruPriceInput),
+		),
+		fyne.NewContainerWithLayout(layout.NewGridLayout(2), previewEarningsButton, savePricingButton),
+		earningsLabel,
+	))
+
+	deriveLocationButton := widget.NewButton("Derive from nodes", func() {
+		farm, ok := editedFarm()
+		if !ok {
+			return
+		}
+		client := expclient
+		var nodes []Node
+		runCancellable("Derive location from nodes", fmt.Sprintf("loading the nodes of farm %s", farm.Name), myWindow, func(ctx context.Context) (err error) {
+			nodes, _, err = ListAllNodesAndNamesContext(ctx, client, farm.ID)
+			return errors.Wrap(err, "failed to list the farm nodes")
+		}, func(err error) {
+			if err != nil {
+				dialog.ShowError(err, myWindow)
+				return
+			}
+			location, count, err := deriveLocation(nodes)
+			if err != nil {
+				dialog.ShowError(err, myWindow)
+				return
+			}
+			dialog.ShowConfirm("Derive location from nodes", fmt.Sprintf("%d of the %d nodes of farm %s are in %s, use this location?", count, len(nodes), farm.Name, location), func(b bool) {
+				if b {
+					farmLocation.Set(location)
+				}
+			}, myWindow)
+		})
+	})
+	saveLocationButton := widget.NewButton("Save location", func() {
+		location, err := farmLocation.Location()
+		if err != nil {
+			dialog.ShowError(err, myWindow)
+			return
+		}
+		confirmFarmUpdate(func(farm *Farm) {
+			farm.Location = location
+		})
+	})
+	farmLocationPanel := container.NewVBox(
+		widget.NewForm(farmLocation.FormItems()...),
+		fyne.NewContainerWithLayout(layout.NewGridLayout(2), deriveLocationButton, saveLocationButton),
+	)
+
+	farmGauges := newResourceGauges()
+	farmNodesLabel := widget.NewLabel("")
+
+	farmEditTabs = container.NewAppTabs(
+		container.NewTabItem("Details", container.NewVBox(
+			formFarmUpdate,
+			fyne.NewContainerWithLayout(layout.NewGridLayout(2),
+				widget.NewButton("Transfer ownership", showTransferDialog),
+				widget.NewButton("Transfer log", showTransferLog),
+			),
+		)),
+		container.NewTabItem("Location", farmLocationPanel),
+		container.NewTabItem("Wallets", farmWalletsPanel),
+		container.NewTabItem("Public IPs", farmIPsPanel),
+		container.NewTabItem("Pricing", farmPricingPanel),
+		container.NewTabItem("Capacity", container.NewVScroll(container.NewVBox(farmNodesLabel, farmGauges.Container))),
+	)
+
 	farmsList := widget.NewListWithData(farmsBinding,
 		func() fyne.CanvasObject {
 			return widget.NewLabel("template")
@@ -255,24 +988,36 @@ func main() {
 		})
 
 	farmsList.OnSelected = func(id widget.ListItemID) {
-		formFarmUpdate.Show()
+		farmEditTabs.Show()
 		farmToEditIdx = int64(id)
 		if id >= len(farmsListData) {
 
 			return
 		}
-		farmNameInputUpdate.SetText(farmsListData[id].Name)
-		for _, x := range farmsListData[id].WalletAddresses {
-			if x.Address != "" && x.Asset != "" {
-				tftAddressInputUpdate.SetText(x.Address)
-				break
-			}
-		}
-		farmOwnerIdEntry.SetText(fmt.Sprintf("%d", farmsListData[id].ThreebotID))
-		farmIdEntryUpdate.SetText(fmt.Sprintf("%d", farmsListData[id].ID))
-		nodesListData, nodesNames = ListAllNodesAndNames(expclient, farmsListData[id].ID)
+		showFarmDetails(farmsListData[id])
+		setEditedWallets(farmsListData[id].WalletAddresses)
+		showFarmIPs(farmsListData[id])
+		showFarmPricing(farmsListData[id])
+		farmLocation.Set(farmsListData[id].Location)
+
+		farm, client := farmsListData[id], expclient
+		nodesListData, nodesNames = make([]Node, 0), make([]string, 0)
 		nodesBinding.Set(nodesNames)
-
+		var nodes []Node
+		var names []string
+		runCancellable("Nodes", fmt.Sprintf("loading the nodes of farm %s", farm.Name), myWindow, func(ctx context.Context) (err error) {
+			nodes, names, err = ListAllNodesAndNamesContext(ctx, client, farm.ID)
+			return errors.Wrap(err, "failed to list the farm nodes")
+		}, func(err error) {
+			if err != nil {
+				dialog.ShowError(err, myWindow)
+			} else {
+				nodesListData, nodesNames = nodes, names
+				nodesBinding.Set(nodesNames)
+			}
+			farmGauges.Set(farmUsage(nodesListData), workloadBreakdown(nodesListData))
+			farmNodesLabel.SetText(fmt.Sprintf("%d nodes", len(nodesListData)))
+		})
 	}
 	scrolledFarmsList := container.NewVScroll(farmsList)
 	scrolledFarmsList.SetMinSize(fyne.NewSize(100, 300))
@@ -289,7 +1034,146 @@ func main() {
 		widget.NewLabel("HRU"), nodeHRU,
 		widget.NewLabel("SRU"), nodeSRU,
 	))
-	nodeDetailsLayout.Hide()
+
+	// nodeDetailsText returns a label for a details tab, scrolled as they can be long
+	nodeDetailsText := func() (*widget.Label, fyne.CanvasObject) {
+		label := widget.NewLabel("")
+		label.TextStyle = fyne.TextStyle{Monospace: true}
+		return label, container.NewScroll(label)
+	}
+	nodeIfacesLabel, nodeIfacesTab := nodeDetailsText()
+	nodePublicLabel, nodePublicScroll := nodeDetailsText()
+	// selectedNode is the ID of the node shown, the nodes list is reloaded when another farm is selected
+	var selectedNode string
+
+	// nodeShown returns the index of the node shown in the nodes list
+	nodeShown := func() (int, bool) {
+		for i, n := range nodesListData {
+			if n.NodeId == selectedNode {
+				return i, true
+			}
+		}
+		return -1, false
+	}
+
+	// showPublicConfigDialog edits the public config of the selected node and
+	// sets it once the changes are confirmed
+	showPublicConfigDialog := func() {
+		idx, ok := nodeShown()
+		if !ok {
+			return
+		}
+		node := nodesListData[idx]
+		current := PublicIface{Type: IfaceTypeMacvlan}
+		if node.PublicConfig != nil {
+			current = *node.PublicConfig
+		}
+
+		masterEntry := widget.NewSelectEntry(ifaceNames(node.Ifaces))
+		masterEntry.SetText(current.Master)
+		masterEntry.SetPlaceHolder("interface of the node")
+		typeSelect := widget.NewSelect(ifaceTypeNames(), nil)
+		typeSelect.SetSelected(current.Type.String())
+		ipv4Entry, gw4Entry := widget.NewEntry(), widget.NewEntry()
+		ipv4Entry.SetText(current.Ipv4)
+		ipv4Entry.SetPlaceHolder("185.69.166.10/24")
+		gw4Entry.SetText(joinIPs([]net.IP{current.Gw4}))
+		gw4Entry.SetPlaceHolder("185.69.166.1")
+		ipv6Entry, gw6Entry := widget.NewEntry(), widget.NewEntry()
+		ipv6Entry.SetText(current.Ipv6)
+		ipv6Entry.SetPlaceHolder("2a02:1802:5e::10/64")
+		gw6Entry.SetText(joinIPs([]net.IP{current.Gw6}))
+		gw6Entry.SetPlaceHolder("2a02:1802:5e::1")
+		// addresses are checked as they are typed, the whole config once submitted
+		for _, entry := range []*widget.Entry{ipv4Entry, ipv6Entry} {
+			entry.Validator = func(text string) error {
+				if _, _, err := net.ParseCIDR(strings.TrimSpace(text)); err != nil && strings.TrimSpace(text) != "" {
+					return fmt.Errorf("expected an ip in CIDR notation")
+				}
+				return nil
+			}
+		}
+
+		dialog.ShowForm(fmt.Sprintf("Public config of node %s", node.NodeId), "Next", "Cancel", []*widget.FormItem{
+			{Text: "Interface", Widget: masterEntry},
+			{Text: "Type", Widget: typeSelect},
+			{Text: "IPv4", Widget: ipv4Entry, HintText: "ip in CIDR notation"},
+			{Text: "IPv4 gateway", Widget: gw4Entry},
+			{Text: "IPv6", Widget: ipv6Entry, HintText: "ip in CIDR notation"},
+			{Text: "IPv6 gateway", Widget: gw6Entry},
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			pub, err := parsePublicConfig(masterEntry.Text, typeSelect.Selected, ipv4Entry.Text, gw4Entry.Text, ipv6Entry.Text, gw6Entry.Text)
+			if err == nil {
+				err = validatePublicConfig(pub, node.Ifaces)
+			}
+			if err != nil {
+				dialog.ShowError(err, myWindow)
+				return
+			}
+
+			message := fmt.Sprintf("Current public config of node %s:\n\n%s\n\nNew public config:\n\n%s", node.NodeId, formatPublicConfig(node.PublicConfig), formatPublicConfig(&pub))
+			dialog.ShowConfirm("Setting node public config", message, func(b bool) {
+				if !b {
+					return
+				}
+				updated, err := setNodePublicConfig(expclient, node, pub)
+				if err != nil {
+					dialog.ShowError(err, myWindow)
+					return
+				}
+				if idx, ok := nodeShown(); ok && nodesListData[idx].NodeId == updated.NodeId {
+					nodesListData[idx] = updated
+				}
+				nodePublicLabel.SetText(formatPublicConfig(updated.PublicConfig))
+				dialog.ShowInformation("Public config set!", fmt.Sprintf("node %s is configured on %s", updated.NodeId, pub.Master), myWindow)
+			}, myWindow)
+		}, myWindow)
+	}
+	nodePublicTab := container.NewBorder(nil, widget.NewButton("Edit public config", showPublicConfigDialog), nil, nil, nodePublicScroll)
+	nodeProofsLabel, nodeProofsTab := nodeDetailsText()
+	nodeStatusLabel, nodeStatusTab := nodeDetailsText()
+	proofsTab := container.NewTabItem("Proofs", nodeProofsTab)
+	nodeGauges := newResourceGauges()
+	nodeDetailsTabs := container.NewAppTabs(
+		container.NewTabItem("Overview", nodeDetailsLayout),
+		container.NewTabItem("Resources", container.NewVScroll(nodeGauges.Container)),
+		container.NewTabItem("Interfaces", nodeIfacesTab),
+		container.NewTabItem("Public config", nodePublicTab),
+		proofsTab,
+		container.NewTabItem("Status", nodeStatusTab),
+	)
+	nodeDetailsTabs.Hide()
+
+	// proofs are only fetched when their tab is shown, in the background so
+	// a slow explorer doesn't freeze the window
+	var proofsNodeID string
+	showNodeProofs := func() {
+		if proofsNodeID == "" || nodeDetailsTabs.CurrentTab() != proofsTab {
+			return
+		}
+		nodeID, client := proofsNodeID, expclient
+		proofsNodeID = ""
+		nodeProofsLabel.SetText("loading proofs...")
+		go func() {
+			node, err := getNodeProofs(client, nodeID)
+			// another node was selected meanwhile
+			if selectedNode != nodeID {
+				return
+			}
+			if err != nil {
+				nodeProofsLabel.SetText(err.Error())
+				proofsNodeID = nodeID
+				return
+			}
+			nodeProofsLabel.SetText(formatProofs(node.Proofs))
+		}()
+	}
+	nodeDetailsTabs.OnChanged = func(*container.TabItem) {
+		showNodeProofs()
+	}
 
 	nodesList := widget.NewListWithData(nodesBinding,
 		func() fyne.CanvasObject {
@@ -299,13 +1183,14 @@ func main() {
 			o.(*widget.Label).Bind(i.(binding.String))
 		})
 	nodesList.OnSelected = func(id widget.ListItemID) {
-		nodeDetailsLayout.Show()
+		nodeDetailsTabs.Show()
 		nodeIdx := int64(id)
 
 		if id > len(nodesNames) {
 			return
 		}
 		nodeSelected := nodesListData[nodeIdx]
+		selectedNode = nodeSelected.NodeId
 		nodeId.SetText(nodeSelected.NodeId)
 		nodeVersion.SetText(nodeSelected.OsVersion)
 		nodeHostName.SetText(nodeSelected.HostName)
@@ -317,27 +1202,376 @@ func main() {
 			nodeLocation.Text += " - " + nodeSelected.Location.City
 		}
 		nodeLocation.Refresh()
-		nodeCRU.SetText(fmt.Sprintf("%d", nodeSelected.TotalResources.Cru))
-		nodeMRU.SetText(fmt.Sprintf("%f", nodeSelected.TotalResources.Mru))
-		nodeSRU.SetText(fmt.Sprintf("%f", nodeSelected.TotalResources.Sru))
-		nodeHRU.SetText(fmt.Sprintf("%f", nodeSelected.TotalResources.Hru))
+		usage := nodeUsage(nodeSelected)
+		for i, entry := range []*widget.Entry{nodeCRU, nodeMRU, nodeSRU, nodeHRU} {
+			entry.SetText(usage[i].format(usage[i].Total))
+		}
+		nodeGauges.Set(usage, workloadBreakdown([]Node{nodeSelected}))
 		t := time.Unix(nodeSelected.Uptime, 0)
 
 		nodeUptime.SetText(fmt.Sprintf("%s", humanize.Time(t)))
+		nodeIfacesLabel.SetText(formatIfaces(nodeSelected.Ifaces))
+		nodePublicLabel.SetText(formatPublicConfig(nodeSelected.PublicConfig))
+		nodeStatusLabel.SetText(formatNodeStatus(nodeSelected))
+		proofsNodeID = nodeSelected.NodeId
+		nodeProofsLabel.SetText("")
+		showNodeProofs()
 
 	}
 	scrolledNodesList := container.NewVScroll(nodesList)
 	nodeDetailsLayout.SetMinSize(fyne.NewSize(100, 400))
 
-	scrolledNodesCont := container.NewVSplit(scrolledNodesList, nodeDetailsLayout)
+	scrolledNodesCont := container.NewVSplit(scrolledNodesList, nodeDetailsTabs)
 
 	// contScrolledList := container.NewVBox(container.NewPadded(), scrolledFarmsList)
-	scolledFarmsListCont := container.NewVSplit(scrolledFarmsList, formFarmUpdate)
+	scolledFarmsListCont := container.NewVSplit(scrolledFarmsList, farmEditTabs)
 
-	formFarmUpdate.Resize(fyne.NewSize(700, 400))
-	formFarmUpdate.Hide()
+	farmEditTabs.Resize(fyne.NewSize(700, 400))
+	farmEditTabs.Hide()
 	contFarmsList := container.NewHSplit(scolledFarmsListCont, scrolledNodesCont)
 
+	// reloadAll resets the farms views and reloads the active identity
+	reloadAll := func() {
+		farmEditTabs.Hide()
+		nodeDetailsTabs.Hide()
+		farmsList.Unselect(int(farmToEditIdx))
+		loadIdentity()
+	}
+
+	showConvertDialog = func(src string) {
+		idEntry := widget.NewEntry()
+		idEntry.SetPlaceHolder("leave empty to look it up on the explorer")
+		convertPassphraseInput := widget.NewPasswordEntry()
+		dialog.ShowForm("Convert legacy seed file", "Convert", "Cancel", []*widget.FormItem{
+			{Text: "Seed file", Widget: widget.NewLabel(src)},
+			{Text: "3Bot ID", Widget: idEntry},
+			{Text: "Passphrase", Widget: convertPassphraseInput, HintText: "optional, encrypts the seed file"},
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			var tid int64
+			if text := strings.TrimSpace(idEntry.Text); text != "" {
+				parsed, err := strconv.ParseInt(text, 10, 64)
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("invalid 3Bot ID: %w", err), myWindow)
+					return
+				}
+				tid = parsed
+			}
+
+			lookup, err := newClient(nil)
+			if err != nil {
+				dialog.ShowError(err, myWindow)
+				return
+			}
+			var ui *UserIdentity
+			var backup string
+			runCancellable("Convert legacy seed file", "looking up your 3Bot on the explorer", myWindow, func(ctx context.Context) (err error) {
+				ui, backup, err = convertLegacySeed(ctx, lookup, src, seedpath, tid, convertPassphraseInput.Text)
+				return err
+			}, func(err error) {
+				if err != nil {
+					dialog.ShowError(err, myWindow)
+					return
+				}
+				reloadAll()
+				dialog.ShowInformation("Seed file converted", fmt.Sprintf("your 3Bot ID is %d and seed is saved at %s\nthe original seed file is kept at %s", ui.ThreebotID, seedpath, backup), myWindow)
+			})
+		}, myWindow)
+	}
+
+	// saveFile asks where to save data and writes it there
+	saveFile := func(data []byte, what string) {
+		dialog.ShowFileSave(func(w fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, myWindow)
+				return
+			}
+			if w == nil {
+				return
+			}
+			defer w.Close()
+			if _, err := w.Write(data); err != nil {
+				dialog.ShowError(errors.Wrapf(err, "failed to write %s", what), myWindow)
+				return
+			}
+			dialog.ShowInformation("Identity backup", fmt.Sprintf("%s saved at %s", what, w.URI().Path()), myWindow)
+		}, myWindow)
+	}
+
+	recoverButton := widget.NewButton("Recover identity from words", func() {
+		recoverWordsEntry := widget.NewMultiLineEntry()
+		recoverPassphraseInput := widget.NewPasswordEntry()
+		dialog.ShowForm("Recover identity", "Recover", "Cancel", []*widget.FormItem{
+			{Text: "Words", Widget: recoverWordsEntry},
+			{Text: "Passphrase", Widget: recoverPassphraseInput, HintText: "optional, encrypts the seed file"},
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+
+			lookup, err := newClient(nil)
+			if err != nil {
+				dialog.ShowError(err, myWindow)
+				return
+			}
+			var ui *UserIdentity
+			var user User
+			runCancellable("Recover identity", "looking up your 3Bot on the explorer", myWindow, func(ctx context.Context) (err error) {
+				ui, user, err = recoverIdentity(ctx, lookup, recoverWordsEntry.Text)
+				return err
+			}, func(err error) {
+				if err != nil {
+					dialog.ShowError(errors.Wrap(err, "failed to recover identity"), myWindow)
+					return
+				}
+
+				save := func() {
+					if err := ui.SaveWithPassphrase(seedpath, recoverPassphraseInput.Text); err != nil {
+						dialog.ShowError(errors.Wrap(err, "failed to save seed"), myWindow)
+						return
+					}
+					reloadAll()
+					dialog.ShowInformation("Identity recovered", fmt.Sprintf("found 3Bot %s with ID %d, seed is saved at %s", user.Name, ui.ThreebotID, seedpath), myWindow)
+				}
+				if seedExists(seedpath) {
+					dialog.ShowConfirm("Overwriting your 3Bot Identity", fmt.Sprintf("Profile %s already has an identity, are you sure you want to overwrite it with 3Bot %s (%d)? Make sure to backup your seed file.", profileNameInput.Text, user.Name, ui.ThreebotID), func(b bool) {
+						if b {
+							save()
+						}
+					}, myWindow)
+					return
+				}
+				save()
+			})
+		}, myWindow)
+	})
+
+	backupButtons := fyne.NewContainerWithLayout(layout.NewGridLayout(3),
+		widget.NewButton("Export identity", func() {
+			if userid.Key().PrivateKey == nil {
+				dialog.ShowInformation("Export identity", "please register or load an identity first", myWindow)
+				return
+			}
+			showPassphraseDialog("Export passphrase", true, myWindow, func(passphrase string) {
+				data, err := exportIdentity(userid, network, passphrase)
+				if err != nil {
+					dialog.ShowError(errors.Wrap(err, "failed to export identity"), myWindow)
+					return
+				}
+				saveFile(data, "encrypted identity")
+			})
+		}),
+		widget.NewButton("Paper backup", func() {
+			if userid.Key().PrivateKey == nil {
+				dialog.ShowInformation("Paper backup", "please register or load an identity first", myWindow)
+				return
+			}
+			data, err := paperBackup(userid, network)
+			if err != nil {
+				dialog.ShowError(errors.Wrap(err, "failed to create paper backup"), myWindow)
+				return
+			}
+			dialog.ShowInformation("Paper backup", "save the page, print it from your browser then delete the file", myWindow)
+			saveFile(data, "paper backup")
+		}),
+		widget.NewButton("Import identity", func() {
+			dialog.ShowFileOpen(func(r fyne.URIReadCloser, err error) {
+				if err != nil {
+					dialog.ShowError(err, myWindow)
+					return
+				}
+				if r == nil {
+					return
+				}
+				data, err := ioutil.ReadAll(r)
+				r.Close()
+				if err != nil {
+					dialog.ShowError(err, myWindow)
+					return
+				}
+
+				showPassphraseDialog("Unlock identity file", false, myWindow, func(passphrase string) {
+					ui, err := importIdentity(network, data, passphrase, clientOptions...)
+					if err != nil {
+						dialog.ShowError(errors.Wrap(err, "failed to import identity"), myWindow)
+						return
+					}
+
+					saveWithPassphrase := func(passphrase string) {
+						if err := ui.SaveWithPassphrase(seedpath, passphrase); err != nil {
+							dialog.ShowError(errors.Wrap(err, "failed to save seed"), myWindow)
+							return
+						}
+						reloadAll()
+						dialog.ShowInformation("Identity imported", fmt.Sprintf("3Bot %d is imported in profile %s", ui.ThreebotID, profileNameInput.Text), myWindow)
+					}
+					// the seed is only encrypted with a new passphrase, not the one of the identity file
+					save := func() {
+						message := "Do you want to encrypt the seed file with a passphrase?"
+						if _, err := readEncryptedSeed(seedpath); err == nil {
+							message = fmt.Sprintf("The seed of profile %s is encrypted, do you want to encrypt the imported one with a passphrase too?", profileNameInput.Text)
+						}
+						dialog.ShowConfirm("Encrypt seed file", message, func(b bool) {
+							if !b {
+								saveWithPassphrase("")
+								return
+							}
+							showPassphraseDialog("Encrypt seed file", true, myWindow, saveWithPassphrase)
+						}, myWindow)
+					}
+					if seedExists(seedpath) {
+						dialog.ShowConfirm("Overwriting your 3Bot Identity", fmt.Sprintf("Profile %s already has an identity, are you sure you want to overwrite it? Make sure to backup your seed file.", profileNameInput.Text), func(b bool) {
+							if b {
+								save()
+							}
+						}, myWindow)
+						return
+					}
+					save()
+				})
+			}, myWindow)
+		}),
+	)
+
+	var tabs *container.AppTabs
+	profileToEditIdx := -1
+	profilesList := widget.NewListWithData(profilesBinding,
+		func() fyne.CanvasObject {
+			return widget.NewLabel("template")
+		},
+		func(i binding.DataItem, o fyne.CanvasObject) {
+			o.(*widget.Label).Bind(i.(binding.String))
+		})
+	profilesList.OnSelected = func(id widget.ListItemID) {
+		profileToEditIdx = id
+	}
+	selectedProfile := func() (Profile, bool) {
+		if profileToEditIdx < 0 || profileToEditIdx >= len(profilesData) {
+			dialog.ShowInformation("Profiles", "please select a profile first", myWindow)
+			return Profile{}, false
+		}
+		return profilesData[profileToEditIdx], true
+	}
+	afterProfileChange := func() {
+		profilesList.Unselect(profileToEditIdx)
+		profileToEditIdx = -1
+		reloadAll()
+	}
+
+	profilesButtons := fyne.NewContainerWithLayout(layout.NewGridLayout(4),
+		widget.NewButton("Use", func() {
+			p, ok := selectedProfile()
+			if !ok {
+				return
+			}
+			if err := setActiveProfile(network, p.Name); err != nil {
+				dialog.ShowError(err, myWindow)
+				return
+			}
+			afterProfileChange()
+		}),
+		widget.NewButton("New", func() {
+			nameEntry := widget.NewEntry()
+			dialog.ShowForm("New profile", "Create", "Cancel", []*widget.FormItem{
+				{Text: "Profile name", Widget: nameEntry, HintText: "letters, digits, '-' and '_'"},
+			}, func(ok bool) {
+				if !ok {
+					return
+				}
+				path, err := getProfileSeedPath(network, nameEntry.Text)
+				if err != nil {
+					dialog.ShowError(err, myWindow)
+					return
+				}
+				if seedExists(path) {
+					dialog.ShowError(fmt.Errorf("profile %s already exists", nameEntry.Text), myWindow)
+					return
+				}
+				if err := setActiveProfile(network, nameEntry.Text); err != nil {
+					dialog.ShowError(err, myWindow)
+					return
+				}
+				afterProfileChange()
+				tabs.SelectTabIndex(0)
+				dialog.ShowInformation("New profile", fmt.Sprintf("register the identity of profile %s from this form", nameEntry.Text), myWindow)
+			}, myWindow)
+		}),
+		widget.NewButton("Import", func() {
+			nameEntry := widget.NewEntry()
+			idEntry := widget.NewEntry()
+			importWordsEntry := widget.NewMultiLineEntry()
+			dialog.ShowForm("Import profile", "Import", "Cancel", []*widget.FormItem{
+				{Text: "Profile name", Widget: nameEntry, HintText: "letters, digits, '-' and '_'"},
+				{Text: "3Bot ID", Widget: idEntry},
+				{Text: "Words", Widget: importWordsEntry},
+			}, func(ok bool) {
+				if !ok {
+					return
+				}
+				tid, err := strconv.ParseInt(strings.TrimSpace(idEntry.Text), 10, 64)
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("invalid 3Bot ID: %w", err), myWindow)
+					return
+				}
+				if _, err := importProfile(network, nameEntry.Text, importWordsEntry.Text, tid, clientOptions...); err != nil {
+					dialog.ShowError(err, myWindow)
+					return
+				}
+				refreshProfiles()
+				dialog.ShowInformation("Profile imported", fmt.Sprintf("profile %s is imported, select it and press Use to activate it", nameEntry.Text), myWindow)
+			}, myWindow)
+		}),
+		widget.NewButton("Remove", func() {
+			p, ok := selectedProfile()
+			if !ok {
+				return
+			}
+			dialog.ShowConfirm("Removing profile", fmt.Sprintf("Are you sure you want to remove profile %s (3Bot ID %d)? Make sure you have its words backed up.", p.Name, p.ThreebotID), func(b bool) {
+				if !b {
+					return
+				}
+				if err := removeProfile(network, p.Name); err != nil {
+					dialog.ShowError(err, myWindow)
+					return
+				}
+				afterProfileChange()
+			}, myWindow)
+		}),
+	)
+	contProfiles := container.NewBorder(nil, profilesButtons, nil, nil, profilesList)
+
+	customExplorerInput := widget.NewEntry()
+	customExplorerInput.SetPlaceHolder("https://explorer.example.com")
+	customExplorerInput.SetText(os.Getenv(explorerEnv))
+	networkSelect := widget.NewSelect(append(append([]string{}, explorersNames...), customNetworkName), func(name string) {
+		if name == customNetworkName {
+			customExplorerInput.Show()
+		} else {
+			customExplorerInput.Hide()
+		}
+	})
+	networkSelect.SetSelected(network.Name)
+	myWindow.SetTitle(fmt.Sprintf("Go Farmer!! - %s", network.Name))
+
+	switchNetwork := widget.NewButton("Switch network", func() {
+		custom := ""
+		if networkSelect.Selected == customNetworkName {
+			custom = customExplorerInput.Text
+		}
+		n, err := resolveNetwork(networkSelect.Selected, custom)
+		if err != nil {
+			dialog.ShowError(err, myWindow)
+			return
+		}
+
+		network = n
+		reloadAll()
+		myWindow.SetTitle(fmt.Sprintf("Go Farmer!! - %s", network.Name))
+		dialog.ShowInformation("Network switched", fmt.Sprintf("now using %s explorer %s", network.Name, network.URL), myWindow)
+	})
+
 	themes := fyne.NewContainerWithLayout(layout.NewGridLayout(2),
 		widget.NewButton("Dark", func() {
 			fyne.CurrentApp().Settings().SetTheme(theme.DarkTheme())
@@ -347,20 +1581,179 @@ func main() {
 		}),
 	)
 
-	tabs := container.NewAppTabs(
-		container.NewTabItem("Identity", formIdentity),
+	tabs = container.NewAppTabs(
+		container.NewTabItem("Identity", container.NewVBox(formIdentity, encryptSeedButton, convertSeedButton, recoverButton, backupButtons)),
+		container.NewTabItem("Profiles", contProfiles),
 		container.NewTabItem("Register Farm", formFarm),
 		container.NewTabItem("Farms", contFarmsList),
-		container.NewTabItem("Settings", themes),
+		container.NewTabItem("Settings", container.NewVBox(
+			widget.NewLabelWithStyle("Network", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			networkSelect,
+			customExplorerInput,
+			switchNetwork,
+			widget.NewSeparator(),
+			widget.NewLabelWithStyle("Theme", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			themes,
+		)),
 	)
 	tabs.SetTabLocation(container.TabLocationLeading)
 
-	myWindow.SetContent(tabs)
+	myWindow.SetContent(container.NewBorder(nil, retryBar, nil, nil, tabs))
 	myWindow.Resize(fyne.NewSize(800, 600))
 
 	myWindow.ShowAndRun()
 }
 
+// showPassphraseDialog asks for a passphrase, twice if confirm is set, and
+// calls onConfirm with it
+func showPassphraseDialog(title string, confirm bool, win fyne.Window, onConfirm func(string)) {
+	passphrase := widget.NewPasswordEntry()
+	again := widget.NewPasswordEntry()
+	items := []*widget.FormItem{{Text: "Passphrase", Widget: passphrase}}
+	if confirm {
+		items = append(items, &widget.FormItem{Text: "Repeat passphrase", Widget: again})
+	}
+
+	dialog.ShowForm(title, "OK", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		if passphrase.Text == "" {
+			dialog.ShowError(fmt.Errorf("passphrase can't be empty"), win)
+			return
+		}
+		if confirm && again.Text != passphrase.Text {
+			dialog.ShowError(fmt.Errorf("passphrases don't match"), win)
+			return
+		}
+		onConfirm(passphrase.Text)
+	}, win)
+}
+
+// runCancellable runs work in the background while a dialog with a Cancel
+// button is shown, cancelling the dialog cancels the context of work. done
+// is called with the error of work, unless it was cancelled
+func runCancellable(title, message string, win fyne.Window, work func(ctx context.Context) error, done func(error)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	bar := widget.NewProgressBarInfinite()
+	d := dialog.NewCustom(title, "Cancel", container.NewVBox(widget.NewLabel(message), bar), win)
+	d.SetOnClosed(cancel)
+	d.Show()
+
+	go func() {
+		err := work(ctx)
+		cancelled := ctx.Err() != nil
+		bar.Stop()
+		d.Hide()
+		if !cancelled {
+			done(err)
+		}
+	}()
+}
+
+// locationEditor edits a farm location in a form
+type locationEditor struct {
+	City      *widget.Entry
+	Country   *widget.SelectEntry
+	Latitude  *widget.Entry
+	Longitude *widget.Entry
+	Continent *widget.Label
+}
+
+func newLocationEditor() *locationEditor {
+	e := &locationEditor{
+		City:      widget.NewEntry(),
+		Country:   widget.NewSelectEntry(countryNames()),
+		Latitude:  widget.NewEntry(),
+		Longitude: widget.NewEntry(),
+		Continent: widget.NewLabel(""),
+	}
+	e.Country.SetPlaceHolder("country name or ISO code")
+	e.Country.Validator = func(text string) error {
+		if _, ok := lookupCountry(text); !ok && strings.TrimSpace(text) != "" {
+			return fmt.Errorf("unknown country %q", text)
+		}
+		return nil
+	}
+	e.Country.OnChanged = func(text string) {
+		c, _ := lookupCountry(text)
+		e.Continent.SetText(c.Continent)
+	}
+	e.Latitude.SetPlaceHolder("-90 to 90, optional")
+	e.Longitude.SetPlaceHolder("-180 to 180, optional")
+	return e
+}
+
+// FormItems returns the form items of the location fields
+func (e *locationEditor) FormItems() []*widget.FormItem {
+	return []*widget.FormItem{
+		{Text: "City", Widget: e.City},
+		{Text: "Country", Widget: e.Country},
+		{Text: "Continent", Widget: e.Continent},
+		{Text: "Latitude", Widget: e.Latitude},
+		{Text: "Longitude", Widget: e.Longitude},
+	}
+}
+
+// Set shows l in the fields
+func (e *locationEditor) Set(l Location) {
+	e.City.SetText(l.City)
+	e.Country.SetText(l.Country)
+	e.Continent.SetText(l.Continent)
+	e.Latitude.SetText("")
+	e.Longitude.SetText("")
+	if l.Latitude != 0 || l.Longitude != 0 {
+		e.Latitude.SetText(strconv.FormatFloat(l.Latitude, 'f', -1, 64))
+		e.Longitude.SetText(strconv.FormatFloat(l.Longitude, 'f', -1, 64))
+	}
+}
+
+// Location returns the validated location of the fields
+func (e *locationEditor) Location() (Location, error) {
+	l := Location{City: e.City.Text, Country: e.Country.Text}
+	var err error
+	if l.Latitude, err = parseCoordinate(e.Latitude.Text); err != nil {
+		return l, errors.Wrap(err, "invalid latitude")
+	}
+	if l.Longitude, err = parseCoordinate(e.Longitude.Text); err != nil {
+		return l, errors.Wrap(err, "invalid longitude")
+	}
+	return normalizeLocation(l)
+}
+
+// resourceGauges shows the usage of the resources and the workloads of a node or a farm
+type resourceGauges struct {
+	bars      []*widget.ProgressBar
+	details   []*widget.Label
+	workloads *widget.Label
+	Container *fyne.Container
+}
+
+func newResourceGauges() *resourceGauges {
+	g := &resourceGauges{workloads: widget.NewLabel("")}
+	g.Container = container.NewVBox()
+	for range farmUsage(nil) {
+		bar, details := widget.NewProgressBar(), widget.NewLabel("")
+		g.bars, g.details = append(g.bars, bar), append(g.details, details)
+		g.Container.Add(bar)
+		g.Container.Add(details)
+	}
+	g.Container.Add(widget.NewLabelWithStyle("Workloads", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	g.Container.Add(g.workloads)
+	return g
+}
+
+// Set shows usage, as returned by farmUsage, and the workloads
+func (g *resourceGauges) Set(usage []resourceUsage, workloads []workloadCount) {
+	for i, u := range usage {
+		u := u
+		g.bars[i].TextFormatter = u.Gauge
+		g.bars[i].SetValue(u.Ratio())
+		g.details[i].SetText(u.String())
+	}
+	g.workloads.SetText(formatWorkloads(workloads))
+}
+
 func validateIdentityData(name, email, words string) []string {
 	errs := make([]string, 0)
 	if name == "" {
@@ -379,7 +1772,6 @@ func validateIdentityData(name, email, words string) []string {
 
 		}
 	}
-	fmt.Println("validation errs: ", errs)
 	return errs
 
 }
@@ -405,59 +1797,92 @@ func validateData(name, email, farm, tftAddress string) []string {
 		errs = append(errs, "farm needs to be alphanumeric")
 	}
 
-	if len(tftAddress) != 56 {
-		errs = append(errs, "invalid tft wallet address")
+	if err := validateStellarAddress(tftAddress); err != nil {
+		errs = append(errs, fmt.Sprintf("invalid tft wallet address: %s", err))
 	}
 	return errs
 
 }
-func registerFarm(expclient *Client, name, email, tftAddress string, tid int) (Farm, error) {
+
+// registerFarm registers a farm paid to the TFT address and the extra wallets
+func registerFarm(expclient *Client, name, email, tftAddress string, wallets []WalletAddress, location Location, tid int) (Farm, error) {
 	name = strings.TrimSpace(name)
 	email = strings.TrimSpace(email)
 	tftAddress = strings.TrimSpace(tftAddress)
-	addresses := make([]WalletAddress, 0)
-	address := WalletAddress{Address: tftAddress, Asset: "TFT"}
-	addresses = append(addresses, address)
+	addresses := []WalletAddress{{Address: tftAddress, Asset: "TFT"}}
+	for _, w := range wallets {
+		addresses = setWalletAddress(addresses, w)
+	}
+	if err := validateWalletAddresses(addresses); err != nil {
+		return Farm{}, err
+	}
+	location, err := normalizeLocation(location)
+	if err != nil {
+		return Farm{}, err
+	}
 	farm := Farm{
 		Name:            name,
 		ThreebotID:      int64(tid),
 		Email:           email,
 		WalletAddresses: addresses,
+		Location:        location,
 	}
 
 	farmID, err := expclient.Directory.FarmRegister(farm)
 	if err != nil {
-		fmt.Println("err:", err)
 		return farm, err
 	}
 	farm.ID = farmID
-	fmt.Println("registered farm: ", farm)
 	return farm, nil
 }
 
-func updateFarm(expclient *Client, farmId, ownerId int64, name, email, tftAddress string, tid int) (Farm, error) {
-	name = strings.TrimSpace(name)
-	email = strings.TrimSpace(email)
-	tftAddress = strings.TrimSpace(tftAddress)
-	addresses := make([]WalletAddress, 0)
-	address := WalletAddress{Address: tftAddress, Asset: "TFT"}
-	addresses = append(addresses, address)
-	farm := Farm{
-		Name:            name,
-		ID:              farmId,
-		ThreebotID:      ownerId,
-		Email:           email,
-		WalletAddresses: addresses,
+// setFarmDetails sets the name, email and TFT address of farm, an empty email
+// keeps the current one. The owner is changed by a farmTransfer
+func setFarmDetails(farm *Farm, name, email, tftAddress string) {
+	farm.Name = strings.TrimSpace(name)
+	if email = strings.TrimSpace(email); email != "" {
+		farm.Email = email
 	}
-	err := expclient.Directory.FarmUpdate(farm)
-	if err != nil {
-		fmt.Println("err:", err)
-		return farm, err
+	farm.WalletAddresses = setWalletAddress(farm.WalletAddresses, WalletAddress{Address: strings.TrimSpace(tftAddress), Asset: "TFT"})
+}
+
+// farmTFTAddress returns the TFT wallet address of farm, or its first address
+// if none is set for TFT
+func farmTFTAddress(farm Farm) string {
+	for _, x := range farm.WalletAddresses {
+		if x.Address != "" && x.Asset == "TFT" {
+			return x.Address
+		}
 	}
-	return farm, nil
+	for _, x := range farm.WalletAddresses {
+		if x.Address != "" && x.Asset != "" {
+			return x.Address
+		}
+	}
+	return ""
+}
+
+// setWalletAddress returns addresses with the address of the asset of
+// address replaced, or added if there is none
+func setWalletAddress(addresses []WalletAddress, address WalletAddress) []WalletAddress {
+	updated := make([]WalletAddress, 0, len(addresses)+1)
+	replaced := false
+	for _, x := range addresses {
+		if x.Asset == address.Asset {
+			if replaced {
+				continue
+			}
+			x, replaced = address, true
+		}
+		updated = append(updated, x)
+	}
+	if !replaced {
+		updated = append(updated, address)
+	}
+	return updated
 }
-func generateID(url, name, email, seedPath, words string) (user User, ui *UserIdentity, err error) {
-	fmt.Println("generating against ", words, seedPath)
+
+func generateID(url, name, email, seedPath, words, passphrase string, opts ...ClientOption) (user User, ui *UserIdentity, err error) {
 	ui = &UserIdentity{}
 	if words != "" {
 		err := ui.FromMnemonic(words)
@@ -467,8 +1892,8 @@ func generateID(url, name, email, seedPath, words string) (user User, ui *UserId
 
 	} else {
 		// check if have the seed path already
-		if _, err = os.Stat(seedPath); !os.IsNotExist(err) {
-			err = ui.Load(seedPath)
+		if seedExists(seedPath) {
+			err = ui.LoadWithPassphrase(seedPath, passphrase)
 			if err != nil {
 				return User{}, ui, err
 			}
@@ -491,7 +1916,7 @@ func generateID(url, name, email, seedPath, words string) (user User, ui *UserId
 		Description: "",
 	}
 
-	httpClient, err := NewClient(url, ui)
+	httpClient, err := NewClient(url, ui, opts...)
 
 	if err != nil {
 		return user, ui, err
@@ -501,8 +1926,6 @@ func generateID(url, name, email, seedPath, words string) (user User, ui *UserId
 	if elerr == nil {
 		// user exists already now we check against the publick key
 		if eluser.Pubkey == hex.EncodeToString(ui.Key().PublicKey) {
-			fmt.Println("user exists an matches explorer registered user pubkey")
-
 			user.ID = eluser.ID
 			ui.ThreebotID = int64(user.ID)
 			return user, ui, nil
@@ -514,44 +1937,99 @@ func generateID(url, name, email, seedPath, words string) (user User, ui *UserId
 
 	id, err := httpClient.Phonebook.Create(user)
 	if err != nil {
-		fmt.Println(err)
 		return user, ui, errors.Wrap(err, "failed to register user")
 	}
 
 	// Update UserData with created id
+	user.ID = id
 	ui.ThreebotID = int64(id)
 
 	// Saving new seed struct
 
-	os.Chmod(seedPath, 0755)
-	if err := ui.Save(seedPath); err != nil {
+	if err := ui.SaveWithPassphrase(seedPath, passphrase); err != nil {
 		return user, ui, errors.Wrap(err, "failed to save seed")
-	} else {
-		fmt.Println("errr: ", err)
 	}
-
-	fmt.Println("Your ID is: ", ui.ThreebotID)
-	fmt.Println("Seed saved in: ", seedPath, " Please make sure you have it backed up.")
 	return user, ui, nil
 }
 
-func getSeedPath() (location string, err error) {
+// getSeedPath returns the seed file of the active profile on network n
+func getSeedPath(n Network) (location string, err error) {
+	name, err := getActiveProfile(n)
+	if err != nil {
+		return "", err
+	}
+
+	return getProfileSeedPath(n, name)
+}
+
+// networkDir returns the directory holding the identities of network n.
+// a 3Bot ID is only valid on the explorer it was registered on, so every
+// network gets its own directory under <UserConfigDir>/tffarmer
+func networkDir(n Network) (string, error) {
 	// Get home directory for current user
 
 	configdDir, err := os.UserConfigDir()
 	if err != nil {
 		return "", err
 	}
-	// os.MkdirAll(expandedDir, 0755)
 
-	path := filepath.Join(configdDir, "tffarmer.seed")
-	return path, nil
+	dir := filepath.Join(configdDir, "tffarmer", n.Key())
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// migrateLegacySeedFile runs migrateLegacySeed on the user config directory,
+// it's done once at startup after the secret store is selected
+func migrateLegacySeedFile() error {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(migrateLegacySeed(configDir), "failed to migrate legacy seed file")
+}
+
+// migrateLegacySeed moves the single <UserConfigDir>/tffarmer.seed used by older
+// versions to the Mainnet directory, the only network they could register on.
+// the seed is moved into the configured secret store
+func migrateLegacySeed(configDir string) error {
+	legacy := filepath.Join(configDir, "tffarmer.seed")
+	if _, err := os.Stat(legacy); os.IsNotExist(err) {
+		return nil
+	}
+
+	mainnet, _ := resolveNetwork("Mainnet", "")
+	dir := filepath.Join(configDir, "tffarmer", mainnet.Key())
+	target := filepath.Join(dir, defaultProfileName+".seed")
+	if seedExists(target) {
+		log.Printf("not migrating %s, %s already exists", legacy, target)
+		return nil
+	}
 
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	log.Printf("migrating seed file %s to %s", legacy, target)
+	data, err := ioutil.ReadFile(legacy)
+	if err != nil {
+		return err
+	}
+	if err := seedStore.Set(target, data); err != nil {
+		return err
+	}
+	return os.Remove(legacy)
 }
 
 // LoadSeed from path
 func LoadSeedData(path string) (string, int, error) {
-	version, seed, err := ReadFile(path)
+	data, err := seedStore.Get(path)
+	if err != nil {
+		return "", 0, err
+	}
+	version, seed, err := ReadBytes(data)
 
 	if version.EQ(SeedVersion11) {
 		// it means we read json data instead of the secret
@@ -567,14 +2045,21 @@ func LoadSeedData(path string) (string, int, error) {
 	}
 	return "", 0, fmt.Errorf("couldn't get mnemonics")
 }
-func ListAllFarmsAndNames(expclient *Client, tid int64) ([]Farm, []string) {
+func ListAllFarmsAndNames(expclient *Client, tid int64) ([]Farm, []string, error) {
+	return ListAllFarmsAndNamesContext(context.Background(), expclient, tid)
+}
+
+// ListAllFarmsAndNamesContext is like ListAllFarmsAndNames but stops listing when ctx is done
+func ListAllFarmsAndNamesContext(ctx context.Context, expclient *Client, tid int64) ([]Farm, []string, error) {
 	farmsRet := make([]Farm, 0)
 	farmsNames := make([]string, 0)
 	pageNumber := 1
 
+	var err error
 	for {
 		pager := Page(pageNumber, 20)
-		farms, err := expclient.Directory.FarmList(tid, "", pager)
+		var farms []Farm
+		farms, err = expclient.Directory.FarmListContext(ctx, tid, "", pager)
 		farmsRet = append(farmsRet, farms...)
 		if err != nil {
 			break
@@ -587,20 +2072,27 @@ func ListAllFarmsAndNames(expclient *Client, tid int64) ([]Farm, []string) {
 	for _, f := range farmsRet {
 		farmsNames = append(farmsNames, f.Name)
 	}
-	return farmsRet, farmsNames
+	return farmsRet, farmsNames, err
+
+}
 
+func ListAllNodesAndNames(expclient *Client, farmId int64) ([]Node, []string, error) {
+	return ListAllNodesAndNamesContext(context.Background(), expclient, farmId)
 }
 
-func ListAllNodesAndNames(expclient *Client, farmId int64) ([]Node, []string) {
+// ListAllNodesAndNamesContext is like ListAllNodesAndNames but stops listing when ctx is done
+func ListAllNodesAndNamesContext(ctx context.Context, expclient *Client, farmId int64) ([]Node, []string, error) {
 	nodesRet := make([]Node, 0)
 	nodesNames := make([]string, 0)
 	pageNumber := 1
 
 	filter := NodeFilter{}
 	filter = filter.WithFarm(farmId)
+	var err error
 	for {
 		pager := Page(pageNumber, 20)
-		nodes, err := expclient.Directory.NodeList(filter, pager)
+		var nodes []Node
+		nodes, err = expclient.Directory.NodeListContext(ctx, filter, pager)
 		nodesRet = append(nodesRet, nodes...)
 		if err != nil {
 			break
@@ -613,6 +2105,6 @@ func ListAllNodesAndNames(expclient *Client, farmId int64) ([]Node, []string) {
 	for _, n := range nodesRet {
 		nodesNames = append(nodesNames, n.NodeId)
 	}
-	return nodesRet, nodesNames
+	return nodesRet, nodesNames, err
 
 }
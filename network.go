@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	// customNetworkName is the name used for user defined explorers (private grids)
+	customNetworkName = "Custom"
+
+	// networkEnv environment variable selecting one of explorersNames
+	networkEnv = "GOFARMER_NETWORK"
+	// explorerEnv environment variable holding a custom explorer url, it takes
+	// precedence over networkEnv
+	explorerEnv = "GOFARMER_EXPLORER"
+	// profileEnv environment variable selecting the profile instead of the active one
+	profileEnv = "GOFARMER_PROFILE"
+	// passphraseEnv environment variable holding the passphrase of encrypted
+	// seed files, for scripts where no terminal is available
+	passphraseEnv = "GOFARMER_PASSPHRASE"
+)
+
+// Network is the explorer the app is talking to
+type Network struct {
+	// Name is one of explorersNames or customNetworkName
+	Name string
+	// URL of the explorer
+	URL string
+}
+
+// Key returns a file system friendly identifier of the network, the name for
+// the known networks and the explorer host for custom ones
+func (n Network) Key() string {
+	if n.Name != customNetworkName {
+		return strings.ToLower(n.Name)
+	}
+
+	key := n.URL
+	if u, err := url.Parse(n.URL); err == nil && u.Host != "" {
+		key = u.Host + u.Path
+	}
+
+	return "custom-" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '_'
+	}, strings.TrimSuffix(key, "/"))
+}
+
+// resolveNetwork returns the network matching name (case insensitive), if
+// custom is not empty it's used as the explorer url of a custom network
+func resolveNetwork(name, custom string) (Network, error) {
+	custom = strings.TrimSpace(custom)
+	if custom != "" {
+		u, err := url.Parse(custom)
+		if err != nil {
+			return Network{}, fmt.Errorf("invalid explorer url: %w", err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Network{}, fmt.Errorf("invalid explorer url %q, expected http(s)://host", custom)
+		}
+		return Network{Name: customNetworkName, URL: strings.TrimSuffix(custom, "/")}, nil
+	}
+
+	if name == "" {
+		name = explorersNames[0]
+	}
+	if strings.EqualFold(name, customNetworkName) {
+		return Network{}, fmt.Errorf("custom network requires an explorer url")
+	}
+
+	for _, n := range explorersNames {
+		if strings.EqualFold(n, name) {
+			return Network{Name: n, URL: explorersUrls[n]}, nil
+		}
+	}
+
+	return Network{}, fmt.Errorf("unknown network %q, expected one of %s", name, strings.Join(explorersNames, ", "))
+}
+
+// networkFromEnv returns the network selected through the environment, it
+// defaults to the first of explorersNames
+func networkFromEnv() (Network, error) {
+	return resolveNetwork(os.Getenv(networkEnv), os.Getenv(explorerEnv))
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// formatIfaces returns the network interfaces of a node, one block per interface
+func formatIfaces(ifaces []Iface) string {
+	if len(ifaces) == 0 {
+		return "no network interfaces reported"
+	}
+	blocks := make([]string, 0, len(ifaces))
+	for _, iface := range ifaces {
+		lines := []string{
+			iface.Name,
+			"  MAC: " + orNone(iface.MacAddress),
+			"  addresses: " + orNone(strings.Join(iface.Addrs, ", ")),
+			"  gateways: " + orNone(joinIPs(iface.Gateway)),
+		}
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// formatPublicConfig returns the public interface configuration of a node
+func formatPublicConfig(pub *PublicIface) string {
+	if pub == nil {
+		return "no public config, the node is not exposed publicly"
+	}
+	lines := []string{
+		"master: " + orNone(pub.Master),
+		"type: " + pub.Type.String(),
+		"IPv4: " + orNone(pub.Ipv4),
+		"IPv4 gateway: " + orNone(joinIPs([]net.IP{pub.Gw4})),
+		"IPv6: " + orNone(pub.Ipv6),
+		"IPv6 gateway: " + orNone(joinIPs([]net.IP{pub.Gw6})),
+		fmt.Sprintf("version: %d", pub.Version),
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatProofs returns the hardware and disk proofs of a node, newest first
+// as the explorer sends them
+func formatProofs(proofs []Proof) string {
+	if len(proofs) == 0 {
+		return "no proofs reported"
+	}
+	blocks := make([]string, 0, len(proofs))
+	for _, p := range proofs {
+		lines := []string{
+			"created: " + orNone(p.Created),
+			"hardware hash: " + orNone(p.HardwareHash),
+			"disk hash: " + orNone(p.DiskHash),
+			"hypervisor: " + orNone(strings.Join(p.Hypervisor, ", ")),
+			"hardware:\n" + formatProofData(p.Hardware),
+			"disks:\n" + formatProofData(p.Disks),
+		}
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// formatProofData returns the free form data of a proof as indented JSON, keys sorted
+func formatProofData(data map[string]interface{}) string {
+	if len(data) == 0 {
+		return "  none"
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, k := range keys {
+		value, err := json.MarshalIndent(data[k], "  ", "  ")
+		if err != nil {
+			value = []byte(fmt.Sprint(data[k]))
+		}
+		lines = append(lines, fmt.Sprintf("  %s: %s", k, value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatNodeStatus returns the status flags and keys of a node
+func formatNodeStatus(n Node) string {
+	ports := make([]string, 0, len(n.WgPorts))
+	for _, p := range n.WgPorts {
+		ports = append(ports, fmt.Sprint(p))
+	}
+	lines := []string{
+		"approved: " + yesNo(n.Approved),
+		"free to use: " + yesNo(n.FreeToUse),
+		"reserved: " + yesNo(n.Reserved),
+		"deleted: " + yesNo(n.Deleted),
+		"public key: " + orNone(n.PublicKeyHex),
+		"wireguard ports: " + orNone(strings.Join(ports, ", ")),
+		"created: " + orNone(n.Created),
+		"updated: " + orNone(n.Updated),
+	}
+	return strings.Join(lines, "\n")
+}
+
+// getNodeProofs returns the node with its proofs, they are not sent when listing nodes
+func getNodeProofs(expclient *Client, nodeID string) (Node, error) {
+	node, err := expclient.Directory.NodeGet(nodeID, true)
+	return node, errors.Wrapf(err, "failed to get the proofs of node %s", nodeID)
+}
+
+func joinIPs(ips []net.IP) string {
+	strs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		if len(ip) != 0 {
+			strs = append(strs, ip.String())
+		}
+	}
+	return strings.Join(strs, ", ")
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// priceCurrencies are all the values of PriceCurrencyEnum
+var priceCurrencies = []PriceCurrencyEnum{
+	PriceCurrencyEUR,
+	PriceCurrencyUSD,
+	PriceCurrencyTFT,
+	PriceCurrencyAED,
+	PriceCurrencyGBP,
+}
+
+// priceCurrencyNames returns the names of the currencies, in enum order
+func priceCurrencyNames() []string {
+	names := make([]string, 0, len(priceCurrencies))
+	for _, c := range priceCurrencies {
+		names = append(names, c.String())
+	}
+	return names
+}
+
+// parsePriceCurrency returns the currency named name, case insensitive
+func parsePriceCurrency(name string) (PriceCurrencyEnum, error) {
+	for _, c := range priceCurrencies {
+		if strings.EqualFold(c.String(), strings.TrimSpace(name)) {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown currency %q, expected one of %s", name, strings.Join(priceCurrencyNames(), ", "))
+}
+
+// parsePrice parses a price entered by the user, empty means 0
+func parsePrice(text string) (float64, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(text, 64)
+}
+
+// formatPrice formats a price without trailing zeros
+func formatPrice(price float64) string {
+	return strconv.FormatFloat(price, 'f', -1, 64)
+}
+
+// farmPricing is the part of a farm edited by the pricing editor
+type farmPricing struct {
+	EnableCustomPricing bool
+	IsGrid3Compliant    bool
+	CloudUnits          NodeCloudUnitPrice
+	// Resources are the resource prices in the currency of CloudUnits
+	Resources NodeResourcePrice
+}
+
+// pricingOf returns the pricing of farm, resource prices are taken in the
+// currency of the cloud units price
+func pricingOf(farm Farm) farmPricing {
+	p := farmPricing{
+		EnableCustomPricing: farm.EnableCustomPricing,
+		IsGrid3Compliant:    farm.IsGrid3Compliant,
+		CloudUnits:          farm.FarmCloudUnitsPrice,
+	}
+	p.Resources = resourcePrice(farm, p.CloudUnits.Currency)
+	return p
+}
+
+// resourcePrice returns the resource prices of farm in currency, zero if none are set
+func resourcePrice(farm Farm, currency PriceCurrencyEnum) NodeResourcePrice {
+	for _, price := range farm.ResourcePrices {
+		if price.Currency == currency {
+			return price
+		}
+	}
+	return NodeResourcePrice{Currency: currency}
+}
+
+// Validate checks that no price is negative
+func (p farmPricing) Validate() error {
+	prices := []struct {
+		name  string
+		value float64
+	}{
+		{"CU", p.CloudUnits.CU},
+		{"SU", p.CloudUnits.SU},
+		{"NU", p.CloudUnits.NU},
+		{"IPv4U", p.CloudUnits.IPv4U},
+		{"CRU", p.Resources.Cru},
+		{"MRU", p.Resources.Mru},
+		{"HRU", p.Resources.Hru},
+		{"SRU", p.Resources.Sru},
+		{"NRU", p.Resources.Nru},
+	}
+	for _, price := range prices {
+		if price.value < 0 || math.IsNaN(price.value) || math.IsInf(price.value, 0) {
+			return fmt.Errorf("%s price should be a positive number", price.name)
+		}
+	}
+	return nil
+}
+
+// apply sets the pricing on farm, resource prices of other currencies are kept
+func (p farmPricing) apply(farm *Farm) {
+	farm.EnableCustomPricing = p.EnableCustomPricing
+	farm.IsGrid3Compliant = p.IsGrid3Compliant
+	farm.FarmCloudUnitsPrice = p.CloudUnits
+
+	resources := p.Resources
+	resources.Currency = p.CloudUnits.Currency
+	prices := make([]NodeResourcePrice, 0, len(farm.ResourcePrices)+1)
+	replaced := false
+	for _, price := range farm.ResourcePrices {
+		if price.Currency == resources.Currency {
+			price, replaced = resources, true
+		}
+		prices = append(prices, price)
+	}
+	if !replaced {
+		prices = append(prices, resources)
+	}
+	farm.ResourcePrices = prices
+}
+
+// nodeEarnings is the monthly earnings estimation of a node
+type nodeEarnings struct {
+	NodeID string
+	// CU and SU are the cloud units provided by the node
+	CU float64
+	SU float64
+	// CloudUnits is the monthly earnings with the cloud units prices
+	CloudUnits float64
+	// Resources is the monthly earnings with the resource prices
+	Resources float64
+	Currency  PriceCurrencyEnum
+}
+
+// cloudUnits returns the compute and storage units of the resources r, using
+// the grid 3 formulas
+//
+//	CU = min((mru - 1) / 4, cru * 4 / 2, sru / 50)
+//	SU = hru / 1200 + sru / 200
+func cloudUnits(r ResourceAmount) (cu, su float64) {
+	cu = math.Min(math.Min((r.Mru-1)/4, float64(r.Cru)*4/2), r.Sru/50)
+	if cu < 0 {
+		cu = 0
+	}
+	su = r.Hru/1200 + r.Sru/200
+	return cu, su
+}
+
+// estimateEarnings returns the monthly earnings of node if all its capacity
+// is used with pricing, network usage and public IPs are not included
+func estimateEarnings(node Node, pricing farmPricing) nodeEarnings {
+	r := node.TotalResources
+	cu, su := cloudUnits(r)
+	return nodeEarnings{
+		NodeID:     node.NodeId,
+		CU:         cu,
+		SU:         su,
+		CloudUnits: cu*pricing.CloudUnits.CU + su*pricing.CloudUnits.SU,
+		Resources:  float64(r.Cru)*pricing.Resources.Cru + r.Mru*pricing.Resources.Mru + r.Hru*pricing.Resources.Hru + r.Sru*pricing.Resources.Sru,
+		Currency:   pricing.CloudUnits.Currency,
+	}
+}
+
+func (e nodeEarnings) String() string {
+	return fmt.Sprintf("%s: %.2f CU, %.2f SU, %.2f %s/month by cloud units, %.2f %s/month by resources",
+		e.NodeID, e.CU, e.SU, e.CloudUnits, e.Currency, e.Resources, e.Currency)
+}
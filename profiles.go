@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultProfileName is the profile used when none was marked active
+	defaultProfileName = "default"
+	// activeProfileFile holds the name of the active profile in a network directory
+	activeProfileFile = "active"
+)
+
+var isProfileName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`).MatchString
+
+// Profile is an identity saved for a network, every profile has its own
+// seed file named <profile>.seed in the network directory
+type Profile struct {
+	Name       string `json:"name"`
+	Network    string `json:"network"`
+	ThreebotID int64  `json:"threebotid"`
+	PublicKey  string `json:"pubkey"`
+	Path       string `json:"path"`
+	Active     bool   `json:"active"`
+	Encrypted  bool   `json:"encrypted"`
+}
+
+func (p Profile) String() string {
+	marker := " "
+	if p.Active {
+		marker = "*"
+	}
+	encrypted := ""
+	if p.Encrypted {
+		encrypted = ", encrypted"
+	}
+	return fmt.Sprintf("%s %s (3Bot ID %d, %s%s) %s", marker, p.Name, p.ThreebotID, p.Network, encrypted, p.PublicKey)
+}
+
+// getProfileSeedPath returns the seed file of profile name on network n
+func getProfileSeedPath(n Network, name string) (string, error) {
+	if !isProfileName(name) {
+		return "", fmt.Errorf("invalid profile name %q, only letters, digits, '-' and '_' are allowed", name)
+	}
+
+	dir, err := networkDir(n)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, name+".seed"), nil
+}
+
+// getActiveProfile returns the name of the active profile on network n
+func getActiveProfile(n Network) (string, error) {
+	dir, err := networkDir(n)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, activeProfileFile))
+	if os.IsNotExist(err) {
+		return defaultProfileName, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	name := strings.TrimSpace(string(data))
+	if !isProfileName(name) {
+		log.Printf("ignoring invalid active profile %q", name)
+		return defaultProfileName, nil
+	}
+
+	return name, nil
+}
+
+// setActiveProfile marks profile name as the active one on network n, the
+// profile seed file doesn't have to exist yet so a new identity can be
+// registered under it
+func setActiveProfile(n Network, name string) error {
+	if !isProfileName(name) {
+		return fmt.Errorf("invalid profile name %q, only letters, digits, '-' and '_' are allowed", name)
+	}
+
+	dir, err := networkDir(n)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, activeProfileFile), []byte(name), 0600)
+}
+
+// listProfiles returns all the profiles saved for network n
+func listProfiles(n Network) ([]Profile, error) {
+	dir, err := networkDir(n)
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := getActiveProfile(n)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := seedStore.List(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make([]Profile, 0, len(keys))
+	for _, path := range keys {
+		if filepath.Ext(path) != ".seed" {
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(path), ".seed")
+		profile := Profile{
+			Name:    name,
+			Network: n.Name,
+			Path:    path,
+			Active:  name == active,
+		}
+
+		ui := &UserIdentity{}
+		if err := ui.Load(path); err == ErrPassphraseRequired {
+			profile.Encrypted = true
+			if e, err := readEncryptedSeed(path); err == nil {
+				profile.ThreebotID = e.ThreebotID
+				profile.PublicKey = e.PublicKey
+			}
+		} else if err != nil {
+			log.Printf("failed to load profile %s: %s", path, err)
+		} else {
+			profile.ThreebotID = ui.ThreebotID
+			profile.PublicKey = hex.EncodeToString(ui.Key().PublicKey)
+		}
+
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, nil
+}
+
+// importProfile saves the identity derived from words as profile name on
+// network n, after making sure the 3Bot tid registered on the explorer
+// has the same public key
+func importProfile(n Network, name, words string, tid int64, opts ...ClientOption) (*UserIdentity, error) {
+	path, err := getProfileSeedPath(n, name)
+	if err != nil {
+		return nil, err
+	}
+	if seedExists(path) {
+		return nil, fmt.Errorf("profile %s already exists", name)
+	}
+
+	ui, err := verifyIdentity(n, words, tid, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ui.Save(path); err != nil {
+		return nil, errors.Wrap(err, "failed to save seed")
+	}
+
+	return ui, nil
+}
+
+// verifyIdentity derives the identity from words and makes sure the 3Bot tid
+// registered on network n has the same public key
+func verifyIdentity(n Network, words string, tid int64, opts ...ClientOption) (*UserIdentity, error) {
+	ui := &UserIdentity{ThreebotID: tid}
+	if err := ui.FromMnemonic(strings.TrimSpace(words)); err != nil {
+		return nil, errors.Wrap(err, "words are invalid")
+	}
+
+	expclient, err := NewClient(n.URL, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	user, err := expclient.Phonebook.Get(tid)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get 3Bot %d", tid)
+	}
+	if !expclient.Phonebook.UserHasSamePublicKey(user, *ui) {
+		return nil, fmt.Errorf("public key of 3Bot %d doesn't match the words", tid)
+	}
+
+	return ui, nil
+}
+
+// removeProfile deletes the seed of profile name on network n
+func removeProfile(n Network, name string) error {
+	path, err := getProfileSeedPath(n, name)
+	if err != nil {
+		return err
+	}
+
+	if err := seedStore.Delete(path); err == ErrSecretNotFound {
+		return fmt.Errorf("profile %s doesn't exist", name)
+	} else if err != nil {
+		return err
+	}
+
+	active, err := getActiveProfile(n)
+	if err != nil || active != name {
+		return err
+	}
+
+	dir, err := networkDir(n)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, activeProfileFile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ifaceTypes are all the values of IfaceTypeEnum
+var ifaceTypes = []IfaceTypeEnum{IfaceTypeMacvlan, IfaceTypeVlan}
+
+// ifaceTypeNames returns the names of the interface types
+func ifaceTypeNames() []string {
+	names := make([]string, 0, len(ifaceTypes))
+	for _, t := range ifaceTypes {
+		names = append(names, t.String())
+	}
+	return names
+}
+
+// parseIfaceType returns the interface type named name, case insensitive
+func parseIfaceType(name string) (IfaceTypeEnum, error) {
+	for _, t := range ifaceTypes {
+		if strings.EqualFold(t.String(), strings.TrimSpace(name)) {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown interface type %q, expected one of %s", name, strings.Join(ifaceTypeNames(), ", "))
+}
+
+// ifaceNames returns the names of the interfaces a node reports
+func ifaceNames(ifaces []Iface) []string {
+	names := make([]string, 0, len(ifaces))
+	for _, iface := range ifaces {
+		names = append(names, iface.Name)
+	}
+	return names
+}
+
+// parsePublicConfig returns the public config of the fields entered by the
+// user, empty gateways are left unset. The config is not validated
+func parsePublicConfig(master, ifaceType, ipv4, gw4, ipv6, gw6 string) (PublicIface, error) {
+	t, err := parseIfaceType(ifaceType)
+	if err != nil {
+		return PublicIface{}, err
+	}
+	pub := PublicIface{
+		Master: strings.TrimSpace(master),
+		Type:   t,
+		Ipv4:   strings.TrimSpace(ipv4),
+		Ipv6:   strings.TrimSpace(ipv6),
+	}
+	gateways := []struct {
+		name  string
+		value string
+		ip    *net.IP
+	}{
+		{"IPv4 gateway", gw4, &pub.Gw4},
+		{"IPv6 gateway", gw6, &pub.Gw6},
+	}
+	for _, gw := range gateways {
+		if strings.TrimSpace(gw.value) == "" {
+			continue
+		}
+		if *gw.ip = net.ParseIP(strings.TrimSpace(gw.value)); *gw.ip == nil {
+			return pub, fmt.Errorf("%s %q is not a valid ip", gw.name, gw.value)
+		}
+	}
+	return pub, nil
+}
+
+// validatePublicConfig checks that the master interface of pub is one of
+// ifaces, when the node reports some, and that its addresses are in CIDR
+// notation with a gateway in their network, or a link-local IPv6 gateway
+func validatePublicConfig(pub PublicIface, ifaces []Iface) error {
+	if pub.Master == "" {
+		return fmt.Errorf("the master interface is required")
+	}
+	if names := ifaceNames(ifaces); len(names) != 0 && !containsString(names, pub.Master) {
+		return fmt.Errorf("the node has no interface %s, expected one of %s", pub.Master, strings.Join(names, ", "))
+	}
+	if pub.Ipv4 == "" && pub.Ipv6 == "" {
+		return fmt.Errorf("an IPv4 or IPv6 address is required")
+	}
+
+	addresses := []struct {
+		family  string
+		address string
+		gateway net.IP
+		v4      bool
+	}{
+		{"IPv4", pub.Ipv4, pub.Gw4, true},
+		{"IPv6", pub.Ipv6, pub.Gw6, false},
+	}
+	for _, a := range addresses {
+		if a.address == "" {
+			if len(a.gateway) != 0 {
+				return fmt.Errorf("%s gateway is set without an %s address", a.family, a.family)
+			}
+			continue
+		}
+		ip, ipnet, err := net.ParseCIDR(a.address)
+		if err != nil || (ip.To4() != nil) != a.v4 {
+			return fmt.Errorf("%s address %q should be an %s in CIDR notation", a.family, a.address, a.family)
+		}
+		if len(a.gateway) == 0 {
+			return fmt.Errorf("%s gateway is required", a.family)
+		}
+		// upstream IPv6 routers are usually reached on their link-local
+		// address, which is outside the network of the address
+		if !a.v4 && a.gateway.To4() == nil && a.gateway.IsLinkLocalUnicast() {
+			if ones, bits := ipnet.Mask.Size(); ones < bits-1 && ip.Equal(ipnet.IP) {
+				return fmt.Errorf("%s address %s is the network address", a.family, a.address)
+			}
+			continue
+		}
+		if err := validatePublicIP(a.address, a.gateway.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setNodePublicConfig validates pub and sets it as the public config of the
+// node, the node is returned as reloaded from the explorer
+func setNodePublicConfig(expclient *Client, node Node, pub PublicIface) (Node, error) {
+	if err := validatePublicConfig(pub, node.Ifaces); err != nil {
+		return node, err
+	}
+	if node.PublicConfig != nil {
+		pub.Version = node.PublicConfig.Version
+	}
+
+	if err := expclient.Directory.NodeSetPublic(node.NodeId, pub); err != nil {
+		return node, errors.Wrapf(err, "failed to set the public config of node %s", node.NodeId)
+	}
+
+	updated, err := expclient.Directory.NodeGet(node.NodeId, false)
+	if err != nil {
+		return node, errors.Wrapf(err, "failed to get node %s", node.NodeId)
+	}
+	return updated, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
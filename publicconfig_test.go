@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestValidatePublicConfig(t *testing.T) {
+	ifaces := []Iface{{Name: "eth0"}, {Name: "eth1"}}
+	for _, test := range []struct {
+		master, ipv4, gw4, ipv6, gw6 string
+		valid                        bool
+	}{
+		{"eth1", "185.69.166.10/24", "185.69.166.1", "", "", true},
+		{"eth1", "", "", "2a02:1802:5e::10/64", "2a02:1802:5e::1", true},
+		// upstream IPv6 routers are usually reached on their link-local address
+		{"eth1", "", "", "2a02:1802:5e::10/64", "fe80::1", true},
+		{"eth1", "185.69.166.10/24", "185.69.166.1", "2a02:1802:5e::10/64", "fe80::1", true},
+		{"eth1", "", "", "2a02:1802:5e::/64", "fe80::1", false},
+		{"eth1", "", "", "2a02:1802:5e::10/64", "2a02:1802:5f::1", false},
+		{"eth1", "185.69.166.10/24", "169.254.0.1", "", "", false},
+		{"eth1", "185.69.166.10/24", "185.69.167.1", "", "", false},
+		{"eth1", "185.69.166.10/24", "fe80::1", "", "", false},
+		{"eth1", "185.69.166.10/24", "", "", "", false},
+		{"eth2", "185.69.166.10/24", "185.69.166.1", "", "", false},
+		{"eth1", "", "", "", "", false},
+	} {
+		pub, err := parsePublicConfig(test.master, "macvlan", test.ipv4, test.gw4, test.ipv6, test.gw6)
+		if err == nil {
+			err = validatePublicConfig(pub, ifaces)
+		}
+		if (err == nil) != test.valid {
+			t.Errorf("%s %s gw %s, %s gw %s: expected valid to be %v, got %v", test.master, test.ipv4, test.gw4, test.ipv6, test.gw6, test.valid, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrLegacySeed is returned when loading a 1.0.0 (binary) seed file
+var ErrLegacySeed = fmt.Errorf("seed file too old, please convert it using 'gofarmer identity convert' command")
+
+// phonebookPageSize is the page size used when walking all the explorer users
+const phonebookPageSize = 100
+
+// findUserByPublicKey walks the explorer phonebook looking for the user
+// registered with the public key pk, until ctx is done
+func findUserByPublicKey(ctx context.Context, expclient *Client, pk []byte) (User, error) {
+	pubkey := hex.EncodeToString(pk)
+	for page := 1; ; page++ {
+		users, err := expclient.Phonebook.ListContext(ctx, "", "", Page(page, phonebookPageSize))
+		if err != nil {
+			return User{}, errors.Wrap(err, "failed to list users")
+		}
+
+		for _, user := range users {
+			if strings.EqualFold(user.Pubkey, pubkey) {
+				return user, nil
+			}
+		}
+
+		if len(users) < phonebookPageSize {
+			return User{}, fmt.Errorf("no user registered with public key %s", pubkey)
+		}
+	}
+}
+
+// readLegacySeed reads the key pair of a 1.0.0 seed, either versioned or raw
+// binary as written by the oldest tools. The seed is read from the seed store,
+// or from the file system when it's a file the store doesn't hold
+func readLegacySeed(path string) (KeyPair, []byte, error) {
+	all, err := seedStore.Get(path)
+	if err == ErrSecretNotFound {
+		all, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return KeyPair{}, nil, err
+	}
+
+	seed := all
+	version, data, err := ReadBytes(all)
+	if err == nil {
+		if version.NE(SeedVersion1) {
+			return KeyPair{}, nil, fmt.Errorf("seed file version is %s, only %s seeds need to be converted", version, SeedVersion1)
+		}
+		seed = data
+	} else if !IsNotVersioned(err) {
+		return KeyPair{}, nil, err
+	}
+
+	pair, err := FromSeed(seed)
+	return pair, all, err
+}
+
+// convertLegacySeed converts the 1.0.0 seed file src into a 1.1.0 seed saved at
+// dst (1.2.0 if passphrase is set). If tid is 0 the 3Bot ID is looked up on
+// the explorer by public key. A copy of src is kept in the seed store next to
+// it and its key is returned
+func convertLegacySeed(ctx context.Context, expclient *Client, src, dst string, tid int64, passphrase string) (*UserIdentity, string, error) {
+	pair, original, err := readLegacySeed(src)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to read legacy seed %s", src)
+	}
+
+	if tid == 0 {
+		user, err := findUserByPublicKey(ctx, expclient, pair.PublicKey)
+		if err != nil {
+			return nil, "", err
+		}
+		tid = user.ID
+	} else {
+		user, err := expclient.Phonebook.GetContext(ctx, tid)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "failed to get 3Bot %d", tid)
+		}
+		if !strings.EqualFold(user.Pubkey, hex.EncodeToString(pair.PublicKey)) {
+			return nil, "", fmt.Errorf("public key of 3Bot %d doesn't match the seed", tid)
+		}
+	}
+
+	backup := fmt.Sprintf("%s.%s.bak", src, time.Now().Format("20060102150405"))
+	if err := seedStore.Set(backup, original); err != nil {
+		return nil, "", errors.Wrap(err, "failed to backup legacy seed")
+	}
+
+	ui := NewUserIdentity(pair, tid)
+	if err := ui.SaveWithPassphrase(dst, passphrase); err != nil {
+		return nil, backup, errors.Wrap(err, "failed to save seed")
+	}
+
+	return ui, backup, nil
+}
+
+// recoverIdentity derives the key from words and looks up the 3Bot registered
+// with its public key on the explorer, so the seed can be restored without
+// knowing the 3Bot ID, name or email
+func recoverIdentity(ctx context.Context, expclient *Client, words string) (*UserIdentity, User, error) {
+	ui := &UserIdentity{}
+	if err := ui.FromMnemonic(strings.TrimSpace(words)); err != nil {
+		return nil, User{}, errors.Wrap(err, "words are invalid")
+	}
+
+	user, err := findUserByPublicKey(ctx, expclient, ui.Key().PublicKey)
+	if err != nil {
+		return nil, User{}, err
+	}
+
+	ui.ThreebotID = user.ID
+	return ui, user, nil
+}
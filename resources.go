@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+// resource names, the explorer reports MRU, SRU and HRU in GiB and CRU in cores
+const (
+	resourceCRU = "CRU"
+	resourceMRU = "MRU"
+	resourceSRU = "SRU"
+	resourceHRU = "HRU"
+)
+
+// resourceUsage is the capacity of a resource of a node or a farm. Used is
+// taken by workloads and Reserved by the node itself
+type resourceUsage struct {
+	Name     string  `json:"name"`
+	Total    float64 `json:"total"`
+	Used     float64 `json:"used"`
+	Reserved float64 `json:"reserved"`
+}
+
+// Free returns the capacity left for new workloads
+func (r resourceUsage) Free() float64 {
+	return math.Max(r.Total-r.Used-r.Reserved, 0)
+}
+
+// Ratio returns the part of the capacity used or reserved, from 0 to 1
+func (r resourceUsage) Ratio() float64 {
+	if r.Total <= 0 {
+		return 0
+	}
+	return math.Min((r.Used+r.Reserved)/r.Total, 1)
+}
+
+// format returns v in human units, cores for CRU and bytes for the others
+func (r resourceUsage) format(v float64) string {
+	if r.Name == resourceCRU {
+		return fmt.Sprintf("%s cores", humanize.Ftoa(math.Round(v*100)/100))
+	}
+	return humanize.IBytes(uint64(math.Max(v, 0) * (1 << 30)))
+}
+
+func (r resourceUsage) String() string {
+	return fmt.Sprintf("%s: %s used, %s reserved, %s free of %s",
+		r.Name, r.format(r.Used), r.format(r.Reserved), r.format(r.Free()), r.format(r.Total))
+}
+
+// Gauge returns the usage as the text of a gauge
+func (r resourceUsage) Gauge() string {
+	return fmt.Sprintf("%s: %s / %s (%.0f%%)", r.Name, r.format(r.Used+r.Reserved), r.format(r.Total), r.Ratio()*100)
+}
+
+// nodeUsage returns the usage of the CRU, MRU, SRU and HRU of n
+func nodeUsage(n Node) []resourceUsage {
+	return farmUsage([]Node{n})
+}
+
+// farmUsage returns the usage of the CRU, MRU, SRU and HRU of all the nodes
+func farmUsage(nodes []Node) []resourceUsage {
+	usage := []resourceUsage{{Name: resourceCRU}, {Name: resourceMRU}, {Name: resourceSRU}, {Name: resourceHRU}}
+	for _, n := range nodes {
+		total, used, reserved := resourceValues(n.TotalResources), resourceValues(n.UsedResources), resourceValues(n.ReservedResources)
+		for i := range usage {
+			usage[i].Total += total[i]
+			usage[i].Used += used[i]
+			usage[i].Reserved += reserved[i]
+		}
+	}
+	return usage
+}
+
+// resourceValues returns the CRU, MRU, SRU and HRU of a
+func resourceValues(a ResourceAmount) []float64 {
+	return []float64{float64(a.Cru), a.Mru, a.Sru, a.Hru}
+}
+
+// workloadCount is the number of workloads of a type deployed on a node
+type workloadCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// workloadBreakdown returns the workloads of all the nodes by type
+func workloadBreakdown(nodes []Node) []workloadCount {
+	counts := []workloadCount{
+		{Name: "networks"}, {Name: "network resources"}, {Name: "volumes"}, {Name: "ZDB namespaces"},
+		{Name: "containers"}, {Name: "kubernetes VMs"}, {Name: "generic VMs"}, {Name: "proxies"},
+		{Name: "reverse proxies"}, {Name: "subdomains"}, {Name: "delegated domains"},
+	}
+	for _, n := range nodes {
+		w := n.Workloads
+		values := []uint16{w.Network, w.NetworkResource, w.Volume, w.ZDBNamespace, w.Container, w.K8sVM,
+			w.GenericVM, w.Proxy, w.ReverseProxy, w.Subdomain, w.DelegateDomain}
+		for i, v := range values {
+			counts[i].Count += int(v)
+		}
+	}
+	return counts
+}
+
+// formatWorkloads returns the workload types that have workloads, one per line
+func formatWorkloads(counts []workloadCount) string {
+	lines := make([]string, 0, len(counts))
+	total := 0
+	for _, c := range counts {
+		if c.Count == 0 {
+			continue
+		}
+		total += c.Count
+		lines = append(lines, fmt.Sprintf("%s: %s", c.Name, humanize.Comma(int64(c.Count))))
+	}
+	if total == 0 {
+		return "no workloads"
+	}
+	return strings.Join(append(lines, fmt.Sprintf("total: %s", humanize.Comma(int64(total)))), "\n")
+}
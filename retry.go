@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RetryPolicy decides how the client retries requests failing with
+// ErrRequestFailure or a 429 or 5xx response.
+//
+// GET, PUT and DELETE requests are idempotent and always retried. POST
+// requests, like a farm registration, are only retried when the explorer
+// could not have handled them: the connection failed or the response is a
+// 429 or 503
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a request is sent, 1 disables retries
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, it doubles on every retry
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts. A request the explorer asks
+	// to retry later than MaxDelay with Retry-After is not retried
+	MaxDelay time.Duration
+	// OnRetry, if set, is called before waiting to retry a request and once
+	// a retried request is done
+	OnRetry func(RetryStatus)
+}
+
+// DefaultRetryPolicy is the retry policy of new clients
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// NoRetry sends requests once
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// RetryStatus describes a request being retried
+type RetryStatus struct {
+	Method string
+	Path   string
+	// Attempt is the attempt that failed, or the last one when Done
+	Attempt     int
+	MaxAttempts int
+	// Delay is the time before the next attempt
+	Delay time.Duration
+	// Err is why the attempt failed, when Done it is nil if the request
+	// finally went through
+	Err error
+	// Done is set once the request is not retried anymore
+	Done bool
+}
+
+func (s RetryStatus) String() string {
+	if s.Done {
+		if s.Err != nil {
+			return fmt.Sprintf("%s %s failed after %d attempts: %s", s.Method, s.Path, s.Attempt, s.Err)
+		}
+		return fmt.Sprintf("%s %s succeeded after %d attempts", s.Method, s.Path, s.Attempt)
+	}
+	return fmt.Sprintf("%s %s failed (attempt %d of %d), retrying in %s: %s",
+		s.Method, s.Path, s.Attempt, s.MaxAttempts, s.Delay.Round(100*time.Millisecond), s.Err)
+}
+
+// next returns the delay before retrying req after its attempt-th attempt
+// ended with response or err, and false if it shouldn't be retried
+func (p RetryPolicy) next(req *http.Request, response *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	idempotent := req.Method != http.MethodPost
+	if err != nil {
+		if !idempotent && !notSent(err) {
+			return 0, false
+		}
+	} else {
+		switch code := response.StatusCode; {
+		case code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable:
+		case code >= 500 && idempotent && code != http.StatusNotImplemented:
+		default:
+			return 0, false
+		}
+	}
+
+	delay := p.backoff(attempt)
+	if response != nil {
+		if after, ok := retryAfter(response, time.Now()); ok {
+			if after > p.MaxDelay {
+				return 0, false
+			}
+			if after > delay {
+				delay = after
+			}
+		}
+	}
+	return delay, true
+}
+
+// backoff returns the delay after the attempt-th attempt, doubling from
+// BaseDelay up to MaxDelay with a random jitter on half of it
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if half := int64(delay / 2); half > 0 {
+		delay = time.Duration(half + rand.Int63n(half+1))
+	}
+	return delay
+}
+
+func (p RetryPolicy) notify(status RetryStatus) {
+	if p.OnRetry != nil {
+		p.OnRetry(status)
+	}
+}
+
+// notSent returns true if err happened before the request was sent
+func notSent(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// retryAfter returns the delay asked by the Retry-After header of response,
+// in seconds or as a date
+func retryAfter(response *http.Response, now time.Time) (time.Duration, bool) {
+	value := response.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
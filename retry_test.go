@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// recordRetries makes the client record the retry statuses it reports
+func recordRetries(client *Client, policy RetryPolicy) func() []RetryStatus {
+	var m sync.Mutex
+	var statuses []RetryStatus
+	policy.OnRetry = func(s RetryStatus) {
+		m.Lock()
+		defer m.Unlock()
+		statuses = append(statuses, s)
+	}
+	client.SetRetryPolicy(policy)
+	return func() []RetryStatus {
+		m.Lock()
+		defer m.Unlock()
+		return append([]RetryStatus(nil), statuses...)
+	}
+}
+
+func TestRetryIdempotent(t *testing.T) {
+	f := newFakeExplorer(t)
+	ui := newTestIdentity(t, f, "farmer")
+	farm := newTestFarm(t, f, ui, "myfarm")
+	client := f.client(t, ui)
+	statuses := recordRetries(client, testRetryPolicy)
+
+	path := fmt.Sprintf("/api/v1/farms/%d", farm.ID)
+	f.failTimes(2, http.MethodGet, path, http.StatusBadGateway, nil, "bad gateway")
+	if _, err := client.Directory.FarmGet(farm.ID); err != nil {
+		t.Fatalf("expected the request to go through on the third attempt, got %v", err)
+	}
+	if n := f.count(http.MethodGet, path); n != 3 {
+		t.Fatalf("expected 3 attempts, got %d", n)
+	}
+	got := statuses()
+	if len(got) != 3 || got[0].Attempt != 1 || got[1].Attempt != 2 || got[0].Done || !got[2].Done || got[2].Err != nil {
+		t.Fatalf("unexpected retry statuses %+v", got)
+	}
+
+	// a signed request is signed again for every attempt
+	f.failTimes(1, http.MethodPut, path, http.StatusServiceUnavailable, nil, "unavailable")
+	farm.Email = "new@example.com"
+	if err := client.Directory.FarmUpdate(farm); err != nil {
+		t.Fatalf("expected the update to be retried, got %v", err)
+	}
+
+	// requests failing on every attempt give up
+	f.fail(http.MethodGet, path, http.StatusInternalServerError, `{"error": "down"}`)
+	_, err := client.Directory.FarmGet(farm.ID)
+	if httpErr, ok := err.(HTTPError); !ok || httpErr.Response().StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected the last error to be returned, got %v", err)
+	}
+	if last := statuses()[len(statuses())-1]; !last.Done || last.Err == nil || last.Attempt != testRetryPolicy.MaxAttempts {
+		t.Fatalf("expected the request to give up after %d attempts, got %+v", testRetryPolicy.MaxAttempts, last)
+	}
+
+	// client errors are not retried
+	before := f.count(http.MethodGet, "/api/v1/farms/42")
+	if _, err := client.Directory.FarmGet(42); err == nil {
+		t.Fatal("expected farm 42 not to be found")
+	}
+	if n := f.count(http.MethodGet, "/api/v1/farms/42") - before; n != 1 {
+		t.Fatalf("expected a single attempt on a 404, got %d", n)
+	}
+}
+
+func TestRetryPost(t *testing.T) {
+	f := newFakeExplorer(t)
+	ui := newTestIdentity(t, f, "farmer")
+	client := f.client(t, ui)
+
+	// the explorer may have registered the farm before failing
+	f.failTimes(1, http.MethodPost, "/api/v1/farms", http.StatusBadGateway, nil, "bad gateway")
+	if _, err := registerFarm(client, "myfarm", "farm@example.com", testAddress, nil, Location{}, int(ui.ThreebotID)); err == nil {
+		t.Fatal("expected the registration to fail")
+	}
+	if n := f.count(http.MethodPost, "/api/v1/farms"); n != 1 {
+		t.Fatalf("expected the registration not to be retried, got %d attempts", n)
+	}
+
+	// it is retried when the explorer refused it
+	f.failTimes(1, http.MethodPost, "/api/v1/farms", http.StatusTooManyRequests, http.Header{"Retry-After": {"0"}}, "slow down")
+	if _, err := registerFarm(client, "myfarm", "farm@example.com", testAddress, nil, Location{}, int(ui.ThreebotID)); err != nil {
+		t.Fatalf("expected the registration to be retried, got %v", err)
+	}
+	if n := f.count(http.MethodPost, "/api/v1/farms"); n != 3 {
+		t.Fatalf("expected a retry, got %d attempts", n-1)
+	}
+
+	// or couldn't be reached
+	f.Close()
+	statuses := recordRetries(client, testRetryPolicy)
+	if _, err := client.Directory.FarmRegister(Farm{Name: "other"}); errors.Cause(err) != ErrRequestFailure {
+		t.Fatalf("expected a request failure, got %v", err)
+	}
+	if got := statuses(); len(got) != testRetryPolicy.MaxAttempts {
+		t.Fatalf("expected the registration to be retried %d times, got %+v", testRetryPolicy.MaxAttempts-1, got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	f := newFakeExplorer(t)
+	client := f.client(t, nil)
+	policy := testRetryPolicy
+	policy.MaxDelay = 50 * time.Millisecond
+	client.SetRetryPolicy(policy)
+
+	// the explorer asks to come back later than the client waits
+	f.failTimes(1, http.MethodGet, "/api/v1/farms", http.StatusServiceUnavailable, http.Header{"Retry-After": {"120"}}, "maintenance")
+	if _, err := client.Directory.FarmList(0, "", nil); err == nil {
+		t.Fatal("expected the request not to be retried")
+	}
+	if n := f.count(http.MethodGet, "/api/v1/farms"); n != 1 {
+		t.Fatalf("expected a single attempt, got %d", n)
+	}
+
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	for value, expected := range map[string]time.Duration{
+		"3": 3 * time.Second,
+		now.Add(time.Minute).Format(http.TimeFormat):  time.Minute,
+		now.Add(-time.Minute).Format(http.TimeFormat): 0,
+	} {
+		response := &http.Response{Header: http.Header{"Retry-After": {value}}}
+		if delay, ok := retryAfter(response, now); !ok || delay != expected {
+			t.Errorf("Retry-After %q: expected %s, got %s (%v)", value, expected, delay, ok)
+		}
+	}
+	if _, ok := retryAfter(&http.Response{Header: http.Header{"Retry-After": {"soon"}}}, now); ok {
+		t.Error("expected an invalid Retry-After to be ignored")
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, max := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 5: time.Second, 9: time.Second} {
+		for i := 0; i < 20; i++ {
+			if delay := policy.backoff(attempt); delay < max/2 || delay > max {
+				t.Fatalf("attempt %d: expected a delay between %s and %s, got %s", attempt, max/2, max, delay)
+			}
+		}
+	}
+}
+
+func TestRetryCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL, nil, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Directory.FarmGetContext(ctx, 1); errors.Cause(err) != context.DeadlineExceeded {
+		t.Fatalf("expected the wait before retrying to be cancelled, got %v", err)
+	}
+}
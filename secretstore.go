@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	// secretStoreEnv environment variable selecting the secret store backend
+	secretStoreEnv = "GOFARMER_SECRET_STORE"
+
+	secretStoreFile          = "file"
+	secretStoreSecretService = "secret-service"
+	secretStoreMemory        = "memory"
+)
+
+var (
+	// ErrSecretNotFound is returned when no secret is stored at a key
+	ErrSecretNotFound = fmt.Errorf("secret not found")
+
+	// seedStore is where the seed files of the identities are kept
+	seedStore SecretStore = &fileStore{}
+)
+
+// SecretStore keeps the seed files of the identities. Keys are the seed
+// paths returned by getProfileSeedPath, backends that don't write to the
+// file system only use them as identifiers
+type SecretStore interface {
+	// Get returns the secret stored at key or ErrSecretNotFound
+	Get(key string) ([]byte, error)
+	// Set stores data at key, replacing any existing secret
+	Set(key string, data []byte) error
+	// Delete removes the secret stored at key or returns ErrSecretNotFound
+	Delete(key string) error
+	// List returns the sorted keys of the secrets stored directly under dir
+	List(dir string) ([]string, error)
+}
+
+// newSecretStore creates the secret store backend kind, one of file
+// (default), secret-service or memory
+func newSecretStore(kind string) (SecretStore, error) {
+	switch kind {
+	case "", secretStoreFile:
+		return &fileStore{}, nil
+	case secretStoreSecretService:
+		return newSecretServiceStore()
+	case secretStoreMemory:
+		return newMemoryStore(), nil
+	}
+
+	return nil, fmt.Errorf("unknown secret store %q, expected one of %s, %s, %s", kind, secretStoreFile, secretStoreSecretService, secretStoreMemory)
+}
+
+// seedExists reports whether a seed is stored at path
+func seedExists(path string) bool {
+	_, err := seedStore.Get(path)
+	return err == nil
+}
+
+// fileStore keeps every secret in a read only file named after its key
+type fileStore struct{}
+
+func (s *fileStore) Get(key string) ([]byte, error) {
+	data, err := ioutil.ReadFile(key)
+	if os.IsNotExist(err) {
+		return nil, ErrSecretNotFound
+	}
+	return data, err
+}
+
+func (s *fileStore) Set(key string, data []byte) error {
+	// the file is read only so make it writable first in case we are overwriting it
+	os.Chmod(key, 0600)
+	file, err := os.OpenFile(key, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0400)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+	_, err = file.Write(data)
+	return err
+}
+
+func (s *fileStore) Delete(key string) error {
+	err := os.Remove(key)
+	if os.IsNotExist(err) {
+		return ErrSecretNotFound
+	}
+	return err
+}
+
+func (s *fileStore) List(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if info.Mode().IsRegular() {
+			keys = append(keys, filepath.Join(dir, info.Name()))
+		}
+	}
+	return keys, nil
+}
+
+// memoryStore keeps the secrets in memory only, nothing is persisted which
+// makes it useful for tests and throwaway sessions
+type memoryStore struct {
+	m       sync.Mutex
+	secrets map[string][]byte
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{secrets: make(map[string][]byte)}
+}
+
+func (s *memoryStore) Get(key string) ([]byte, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	data, ok := s.secrets[key]
+	if !ok {
+		return nil, ErrSecretNotFound
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (s *memoryStore) Set(key string, data []byte) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.secrets[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *memoryStore) Delete(key string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if _, ok := s.secrets[key]; !ok {
+		return ErrSecretNotFound
+	}
+	delete(s.secrets, key)
+	return nil
+}
+
+func (s *memoryStore) List(dir string) ([]string, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	keys := make([]string, 0)
+	for key := range s.secrets {
+		if filepath.Dir(key) == filepath.Clean(dir) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,220 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/pkg/errors"
+)
+
+const (
+	secretServiceDest           = "org.freedesktop.secrets"
+	secretServicePath           = dbus.ObjectPath("/org/freedesktop/secrets")
+	secretServiceDefaultAlias   = dbus.ObjectPath("/org/freedesktop/secrets/aliases/default")
+	secretServiceInterface      = "org.freedesktop.Secret.Service"
+	secretCollectionInterface   = "org.freedesktop.Secret.Collection"
+	secretItemInterface         = "org.freedesktop.Secret.Item"
+	secretPromptInterface       = "org.freedesktop.Secret.Prompt"
+	secretServiceApplicationTag = "gofarmer"
+)
+
+// secretServiceSecret is the Secret struct of the Secret Service API
+type secretServiceSecret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// secretServiceStore keeps the secrets in the default collection of the
+// freedesktop Secret Service (gnome-keyring, KWallet...) over D-Bus.
+// secrets are looked up by attributes: the application, the key and the
+// directory of the key so they can be listed per network
+type secretServiceStore struct {
+	conn    *dbus.Conn
+	service dbus.BusObject
+	session dbus.ObjectPath
+}
+
+func newSecretServiceStore() (SecretStore, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to the session bus")
+	}
+
+	s := &secretServiceStore{
+		conn:    conn,
+		service: conn.Object(secretServiceDest, secretServicePath),
+	}
+
+	// the plain algorithm sends the secrets unencrypted over the session bus
+	// which is only reachable by the current user
+	var output dbus.Variant
+	err = s.service.Call(secretServiceInterface+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &s.session)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open secret service session")
+	}
+
+	return s, nil
+}
+
+func (s *secretServiceStore) attributes(key string) map[string]string {
+	return map[string]string{
+		"application": secretServiceApplicationTag,
+		"key":         key,
+		"dir":         filepath.Dir(key),
+	}
+}
+
+func (s *secretServiceStore) search(attributes map[string]string) ([]dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	err := s.service.Call(secretServiceInterface+".SearchItems", 0, attributes).Store(&unlocked, &locked)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to search secret service items")
+	}
+
+	if len(locked) == 0 {
+		return unlocked, nil
+	}
+
+	var prompt dbus.ObjectPath
+	var newlyUnlocked []dbus.ObjectPath
+	if err := s.service.Call(secretServiceInterface+".Unlock", 0, locked).Store(&newlyUnlocked, &prompt); err != nil {
+		return nil, errors.Wrap(err, "failed to unlock secret service items")
+	}
+	if err := s.prompt(prompt); err != nil {
+		return nil, err
+	}
+
+	return append(unlocked, locked...), nil
+}
+
+// prompt shows the secret service prompt (e.g. to unlock the keyring) and
+// waits for the user to complete it
+func (s *secretServiceStore) prompt(path dbus.ObjectPath) error {
+	if path == "/" || path == "" {
+		return nil
+	}
+
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(path),
+		dbus.WithMatchInterface(secretPromptInterface),
+		dbus.WithMatchMember("Completed"),
+	}
+	if err := s.conn.AddMatchSignal(match...); err != nil {
+		return err
+	}
+	defer s.conn.RemoveMatchSignal(match...)
+
+	signals := make(chan *dbus.Signal, 1)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+
+	if err := s.conn.Object(secretServiceDest, path).Call(secretPromptInterface+".Prompt", 0, "").Err; err != nil {
+		return errors.Wrap(err, "failed to show secret service prompt")
+	}
+
+	for signal := range signals {
+		if signal.Path != path || len(signal.Body) == 0 {
+			continue
+		}
+		if dismissed, ok := signal.Body[0].(bool); ok && dismissed {
+			return fmt.Errorf("secret service prompt dismissed")
+		}
+		return nil
+	}
+
+	return nil
+}
+
+func (s *secretServiceStore) Get(key string) ([]byte, error) {
+	items, err := s.search(s.attributes(key))
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, ErrSecretNotFound
+	}
+
+	var secret secretServiceSecret
+	err = s.conn.Object(secretServiceDest, items[0]).Call(secretItemInterface+".GetSecret", 0, s.session).Store(&secret)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get secret")
+	}
+
+	return secret.Value, nil
+}
+
+func (s *secretServiceStore) Set(key string, data []byte) error {
+	properties := map[string]dbus.Variant{
+		secretItemInterface + ".Label":      dbus.MakeVariant(fmt.Sprintf("gofarmer seed %s/%s", filepath.Base(filepath.Dir(key)), strings.TrimSuffix(filepath.Base(key), filepath.Ext(key)))),
+		secretItemInterface + ".Attributes": dbus.MakeVariant(s.attributes(key)),
+	}
+	secret := secretServiceSecret{
+		Session:     s.session,
+		Parameters:  []byte{},
+		Value:       data,
+		ContentType: "application/octet-stream",
+	}
+
+	var item, prompt dbus.ObjectPath
+	collection := s.conn.Object(secretServiceDest, secretServiceDefaultAlias)
+	err := collection.Call(secretCollectionInterface+".CreateItem", 0, properties, secret, true).Store(&item, &prompt)
+	if err != nil {
+		return errors.Wrap(err, "failed to store secret")
+	}
+
+	return s.prompt(prompt)
+}
+
+func (s *secretServiceStore) Delete(key string) error {
+	items, err := s.search(s.attributes(key))
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return ErrSecretNotFound
+	}
+
+	for _, item := range items {
+		var prompt dbus.ObjectPath
+		if err := s.conn.Object(secretServiceDest, item).Call(secretItemInterface+".Delete", 0).Store(&prompt); err != nil {
+			return errors.Wrap(err, "failed to delete secret")
+		}
+		if err := s.prompt(prompt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *secretServiceStore) List(dir string) ([]string, error) {
+	items, err := s.search(map[string]string{
+		"application": secretServiceApplicationTag,
+		"dir":         filepath.Clean(dir),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		variant, err := s.conn.Object(secretServiceDest, item).GetProperty(secretItemInterface + ".Attributes")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get secret attributes")
+		}
+		if attributes, ok := variant.Value().(map[string]string); ok && attributes["key"] != "" {
+			keys = append(keys, attributes["key"])
+		}
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
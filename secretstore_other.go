@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import "fmt"
+
+func newSecretServiceStore() (SecretStore, error) {
+	return nil, fmt.Errorf("the secret service store is only supported on linux")
+}
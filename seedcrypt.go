@@ -0,0 +1,161 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+const (
+	seedKDFArgon2id = "argon2id"
+
+	// argon2id parameters recommended by the RFC draft for interactive use
+	seedKDFTime    = 3
+	seedKDFMemory  = 64 * 1024
+	seedKDFThreads = 4
+
+	// bounds of the argon2id parameters accepted from seed files, so a corrupt
+	// or crafted file can't make the key derivation panic or exhaust memory
+	seedKDFMaxTime   = 64
+	seedKDFMaxMemory = 1024 * 1024 // KiB
+	seedSaltMinSize  = 8
+	seedSaltMaxSize  = 64
+	seedNonceSize    = 24
+)
+
+var (
+	// ErrPassphraseRequired is returned when loading an encrypted seed file without a passphrase
+	ErrPassphraseRequired = fmt.Errorf("seed file is encrypted, a passphrase is required")
+	// ErrWrongPassphrase is returned when an encrypted seed file can't be opened with the passphrase
+	ErrWrongPassphrase = fmt.Errorf("wrong passphrase or corrupted seed file")
+)
+
+// encryptedSeed is the payload of a 1.2.0 seed file. The 1.1.0 json payload is
+// sealed with secretbox using a key derived from the passphrase with argon2id,
+// the 3Bot ID and public key are kept in clear so profiles can be listed
+// without asking for the passphrase
+type encryptedSeed struct {
+	ThreebotID int64  `json:"threebotid"`
+	PublicKey  string `json:"pubkey"`
+	KDF        string `json:"kdf"`
+	Time       uint32 `json:"time"`
+	Memory     uint32 `json:"memory"`
+	Threads    uint8  `json:"threads"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Box        []byte `json:"box"`
+}
+
+// check returns an error if the parameters of e can't be used to derive its key
+func (e *encryptedSeed) check() error {
+	switch {
+	case e.KDF != seedKDFArgon2id:
+		return fmt.Errorf("unsupported key derivation function %q", e.KDF)
+	case e.Time == 0 || e.Time > seedKDFMaxTime:
+		return fmt.Errorf("invalid key derivation time %d", e.Time)
+	case e.Threads == 0:
+		return fmt.Errorf("invalid key derivation threads %d", e.Threads)
+	case e.Memory < 8*uint32(e.Threads) || e.Memory > seedKDFMaxMemory:
+		return fmt.Errorf("invalid key derivation memory %d KiB", e.Memory)
+	case len(e.Salt) < seedSaltMinSize || len(e.Salt) > seedSaltMaxSize:
+		return fmt.Errorf("invalid salt size %d", len(e.Salt))
+	case len(e.Nonce) != seedNonceSize:
+		return fmt.Errorf("invalid nonce size %d", len(e.Nonce))
+	}
+	return nil
+}
+
+func (e *encryptedSeed) key(passphrase string) [32]byte {
+	var key [32]byte
+	copy(key[:], argon2.IDKey([]byte(passphrase), e.Salt, e.Time, e.Memory, e.Threads, 32))
+	return key
+}
+
+// newEncryptedSeed seals payload with a key derived from passphrase
+func newEncryptedSeed(payload []byte, passphrase string, threebotID int64, publicKey string) (encryptedSeed, error) {
+	e := encryptedSeed{
+		ThreebotID: threebotID,
+		PublicKey:  publicKey,
+		KDF:        seedKDFArgon2id,
+		Time:       seedKDFTime,
+		Memory:     seedKDFMemory,
+		Threads:    seedKDFThreads,
+		Salt:       make([]byte, 16),
+		Nonce:      make([]byte, seedNonceSize),
+	}
+
+	if _, err := rand.Read(e.Salt); err != nil {
+		return e, err
+	}
+	if _, err := rand.Read(e.Nonce); err != nil {
+		return e, err
+	}
+
+	var nonce [seedNonceSize]byte
+	copy(nonce[:], e.Nonce)
+	key := e.key(passphrase)
+	e.Box = secretbox.Seal(nil, payload, &nonce, &key)
+
+	return e, nil
+}
+
+// open returns the payload sealed in e
+func (e *encryptedSeed) open(passphrase string) ([]byte, error) {
+	if err := e.check(); err != nil {
+		return nil, err
+	}
+
+	var nonce [seedNonceSize]byte
+	copy(nonce[:], e.Nonce)
+	key := e.key(passphrase)
+	payload, ok := secretbox.Open(nil, e.Box, &nonce, &key)
+	if !ok {
+		return nil, ErrWrongPassphrase
+	}
+
+	return payload, nil
+}
+
+// sealSeed encrypts the 1.1.0 payload with passphrase
+func sealSeed(payload []byte, passphrase string, threebotID int64, publicKey string) ([]byte, error) {
+	e, err := newEncryptedSeed(payload, passphrase, threebotID, publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(e)
+}
+
+// openSeed decrypts a 1.2.0 payload with passphrase and returns the 1.1.0 payload
+func openSeed(data []byte, passphrase string) ([]byte, error) {
+	var e encryptedSeed
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+
+	return e.open(passphrase)
+}
+
+// readEncryptedSeed returns the clear part of an encrypted seed
+func readEncryptedSeed(path string) (encryptedSeed, error) {
+	var e encryptedSeed
+	all, err := seedStore.Get(path)
+	if err != nil {
+		return e, err
+	}
+	version, data, err := ReadBytes(all)
+	if err != nil {
+		return e, err
+	}
+	if version.NE(SeedVersion12) {
+		return e, fmt.Errorf("seed file is not encrypted")
+	}
+
+	if err := json.Unmarshal(data, &e); err != nil {
+		return e, err
+	}
+	return e, e.check()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEncryptedSeedTamperedHeader(t *testing.T) {
+	useMemoryStore(t)
+	k, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const path, passphrase = "test.seed", "secret"
+	if err := NewUserIdentity(k, 42).SaveWithPassphrase(path, passphrase); err != nil {
+		t.Fatal(err)
+	}
+	all, err := seedStore.Get(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, data, err := ReadBytes(all)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tamper := range map[string]func(header map[string]interface{}){
+		"kdf":         func(h map[string]interface{}) { h["kdf"] = "scrypt" },
+		"no time":     func(h map[string]interface{}) { h["time"] = 0 },
+		"long time":   func(h map[string]interface{}) { h["time"] = 1 << 20 },
+		"no threads":  func(h map[string]interface{}) { h["threads"] = 0 },
+		"no memory":   func(h map[string]interface{}) { h["memory"] = 0 },
+		"huge memory": func(h map[string]interface{}) { h["memory"] = 1 << 31 },
+		"short salt":  func(h map[string]interface{}) { h["salt"] = []byte{1, 2, 3} },
+		"short nonce": func(h map[string]interface{}) { h["nonce"] = make([]byte, 12) },
+	} {
+		var header map[string]interface{}
+		if err := json.Unmarshal(data, &header); err != nil {
+			t.Fatal(err)
+		}
+		tamper(header)
+		tampered, err := json.Marshal(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seed, err := WriteBytes(SeedVersion12, tampered)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := seedStore.Set(path, seed); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := readEncryptedSeed(path); err == nil {
+			t.Errorf("%s: expected the header to be refused", name)
+		}
+		if err := (&UserIdentity{}).LoadWithPassphrase(path, passphrase); err == nil || err == ErrWrongPassphrase {
+			t.Errorf("%s: expected the parameters to be refused before deriving the key, got %v", name, err)
+		}
+	}
+
+	// the untouched seed still opens
+	if err := seedStore.Set(path, all); err != nil {
+		t.Fatal(err)
+	}
+	ui := &UserIdentity{}
+	if err := ui.LoadWithPassphrase(path, passphrase); err != nil || ui.ThreebotID != 42 {
+		t.Fatalf("expected the seed to open, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// strKeyVersionAccountID is the version byte of stellar account IDs
+	// (public keys), it encodes to a G as first character
+	strKeyVersionAccountID byte = 6 << 3
+	// strKeyVersionSeed is the version byte of stellar secret seeds, it
+	// encodes to an S as first character
+	strKeyVersionSeed byte = 18 << 3
+
+	// strKeyLength is the length of an encoded ed25519 stellar key
+	strKeyLength = 56
+)
+
+var (
+	// ErrStrKeyLength is returned when a stellar address doesn't have 56 characters
+	ErrStrKeyLength = fmt.Errorf("a stellar address has %d characters", strKeyLength)
+	// ErrStrKeySecretSeed is returned when a stellar secret key is given instead of an address
+	ErrStrKeySecretSeed = fmt.Errorf("this is a secret key, never share it! use the public address starting with G instead")
+	// ErrStrKeyPrefix is returned when a stellar address doesn't start with G
+	ErrStrKeyPrefix = fmt.Errorf("a stellar address starts with G")
+	// ErrStrKeyEncoding is returned when a stellar address is not valid base32
+	ErrStrKeyEncoding = fmt.Errorf("a stellar address only has the letters A to Z and the digits 2 to 7")
+	// ErrStrKeyChecksum is returned when the checksum of a stellar address doesn't match, mostly a typo
+	ErrStrKeyChecksum = fmt.Errorf("invalid checksum, check the address for typos")
+)
+
+// strKeyEncoding is the base32 encoding used by stellar keys
+var strKeyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// decodeStrKey decodes the stellar key s of the version, a base32 string of
+// the version byte, the 32 bytes key and a CRC16-XModem checksum of both.
+// The key is returned
+func decodeStrKey(version byte, s string) ([]byte, error) {
+	raw, err := strKeyEncoding.DecodeString(s)
+	if err != nil || len(raw) != 35 {
+		return nil, ErrStrKeyEncoding
+	}
+
+	payload, checksum := raw[:len(raw)-2], binary.LittleEndian.Uint16(raw[len(raw)-2:])
+	if crc16XModem(payload) != checksum {
+		return nil, ErrStrKeyChecksum
+	}
+
+	switch {
+	case payload[0] == version:
+		return payload[1:], nil
+	case payload[0] == strKeyVersionSeed:
+		return nil, ErrStrKeySecretSeed
+	}
+	return nil, ErrStrKeyPrefix
+}
+
+// validateStellarAddress checks that address is a stellar account ID, the
+// error says what is wrong with it
+func validateStellarAddress(address string) error {
+	address = strings.TrimSpace(address)
+	short := address
+	if len(short) > 8 {
+		short = short[:4] + "..." + short[len(short)-4:]
+	}
+
+	// a pasted secret key is reported before anything else so it's not
+	// shown back in the error
+	if strings.HasPrefix(address, "S") {
+		return ErrStrKeySecretSeed
+	}
+	if len(address) != strKeyLength {
+		return errors.Wrapf(ErrStrKeyLength, "stellar address %s has %d characters", short, len(address))
+	}
+	if !strings.HasPrefix(address, "G") {
+		return errors.Wrapf(ErrStrKeyPrefix, "stellar address %s", short)
+	}
+
+	_, err := decodeStrKey(strKeyVersionAccountID, address)
+	if err == ErrStrKeySecretSeed {
+		return err
+	}
+	return errors.Wrapf(err, "stellar address %s", short)
+}
+
+// crc16XModem computes the CRC16-XModem checksum used by stellar keys
+func crc16XModem(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
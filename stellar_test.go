@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// encodeStrKey encodes key as a stellar key of the version
+func encodeStrKey(version byte, key []byte) string {
+	payload := append([]byte{version}, key...)
+	checksum := make([]byte, 2)
+	binary.LittleEndian.PutUint16(checksum, crc16XModem(payload))
+	return strKeyEncoding.EncodeToString(append(payload, checksum...))
+}
+
+func TestCRC16XModem(t *testing.T) {
+	// check value of the CRC-16/XMODEM catalogue
+	if crc := crc16XModem([]byte("123456789")); crc != 0x31C3 {
+		t.Fatalf("expected 0x31C3, got %#x", crc)
+	}
+}
+
+func TestValidateStellarAddress(t *testing.T) {
+	// a typo in the middle of the address
+	typo := []byte(testAddress)
+	typo[20] = 'A'
+
+	for _, test := range []struct {
+		address  string
+		expected error
+	}{
+		{testAddress, nil},
+		{"GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H", nil},
+		{"GCFXHS4GXL6BVUCXBWXGTITROWLVYXQKQLF4YH5O5JT3YZXCYPAFBJZB", nil},
+		{"G" + strings.Repeat("A", 52) + "WHF", nil},
+		{"  " + testAddress + "\n", nil},
+		{string(typo), ErrStrKeyChecksum},
+		{testAddress[:55] + "6", ErrStrKeyChecksum},
+		{encodeStrKey(strKeyVersionAccountID|1, make([]byte, 32)), ErrStrKeyPrefix},
+		{encodeStrKey(12<<3, make([]byte, 32)), ErrStrKeyPrefix},
+		{testAddress[:55], ErrStrKeyLength},
+		{testAddress + "A", ErrStrKeyLength},
+		{"", ErrStrKeyLength},
+		{strings.ToLower(testAddress[:1]) + testAddress[1:], ErrStrKeyPrefix},
+		{testAddress[:30] + "1" + testAddress[31:], ErrStrKeyEncoding},
+		{"SBU2RRGLXH3E5CQHTD3ODLDF2BWDCYUSSBLLZ5GNW7JXHDIYKXZWHOKR", ErrStrKeySecretSeed},
+	} {
+		if err := validateStellarAddress(test.address); errors.Cause(err) != test.expected {
+			t.Errorf("%q: expected %v, got %v", test.address, test.expected, err)
+		}
+	}
+}
+
+func TestDecodeStrKey(t *testing.T) {
+	key := bytes.Repeat([]byte{0xab}, 32)
+	decoded, err := decodeStrKey(strKeyVersionAccountID, encodeStrKey(strKeyVersionAccountID, key))
+	if err != nil || !bytes.Equal(decoded, key) {
+		t.Fatalf("expected the key back, got %x (%v)", decoded, err)
+	}
+	if decoded, err := decodeStrKey(strKeyVersionAccountID, "G"+strings.Repeat("A", 52)+"WHF"); err != nil || !bytes.Equal(decoded, make([]byte, 32)) {
+		t.Fatalf("expected the zero key, got %x (%v)", decoded, err)
+	}
+
+	// a secret seed is reported as such even though its checksum is valid
+	seed := encodeStrKey(strKeyVersionSeed, key)
+	if _, err := decodeStrKey(strKeyVersionAccountID, seed); err != ErrStrKeySecretSeed {
+		t.Errorf("expected a secret seed, got %v", err)
+	}
+	if _, err := decodeStrKey(strKeyVersionSeed, testAddress); err != ErrStrKeyPrefix {
+		t.Errorf("expected a wrong version byte, got %v", err)
+	}
+	if _, err := decodeStrKey(strKeyVersionAccountID, encodeStrKey(strKeyVersionAccountID, key[:31])); err != ErrStrKeyEncoding {
+		t.Errorf("expected a short key to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// transferLogFile is the audit log of the farm transfers made on a network,
+// in the network directory
+const transferLogFile = "transfers.log"
+
+// statuses of a farm transfer in the audit log
+const (
+	transferSigned   = "signed"
+	transferDone     = "done"
+	transferFailed   = "failed"
+	transferDeclined = "declined"
+)
+
+// farmTransfer is a farm ownership transfer, signed by the current owner
+// before it's sent and recorded in the audit log
+type farmTransfer struct {
+	Time     time.Time `json:"time"`
+	Network  string    `json:"network"`
+	FarmID   int64     `json:"farm_id"`
+	FarmName string    `json:"farm_name"`
+	From     int64     `json:"from"`
+	To       int64     `json:"to"`
+	ToName   string    `json:"to_name"`
+	ToEmail  string    `json:"to_email"`
+	// Message and Signature are the hex encoded transfer intent and its
+	// signature by the current owner
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (t farmTransfer) String() string {
+	s := fmt.Sprintf("%s farm %d (%s) from %d to %d (%s, %s): %s",
+		t.Time.Local().Format(time.RFC3339), t.FarmID, t.FarmName, t.From, t.To, t.ToName, t.ToEmail, t.Status)
+	if t.Error != "" {
+		s += ": " + t.Error
+	}
+	return s
+}
+
+// newFarmTransfer checks that the farm of ui can be transferred to the 3Bot
+// to, which is resolved on the phonebook, and signs the transfer intent
+func newFarmTransfer(expclient *Client, n Network, ui *UserIdentity, farm Farm, to int64) (*farmTransfer, error) {
+	if ui == nil || ui.Key().PrivateKey == nil {
+		return nil, fmt.Errorf("an identity is required to transfer a farm")
+	}
+	if farm.ThreebotID != ui.ThreebotID {
+		return nil, fmt.Errorf("farm %s is owned by %d, only its owner can transfer it", farm.Name, farm.ThreebotID)
+	}
+	if to <= 0 {
+		return nil, fmt.Errorf("invalid 3Bot ID %d", to)
+	}
+	if to == farm.ThreebotID {
+		return nil, fmt.Errorf("farm %s is already owned by %d", farm.Name, to)
+	}
+
+	user, err := expclient.Phonebook.Get(to)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find 3Bot %d", to)
+	}
+
+	t := &farmTransfer{
+		Time:     time.Now().UTC(),
+		Network:  n.Key(),
+		FarmID:   farm.ID,
+		FarmName: farm.Name,
+		From:     farm.ThreebotID,
+		To:       to,
+		ToName:   user.Name,
+		ToEmail:  user.Email,
+	}
+
+	signer, err := NewSigner(ui.Key().PrivateKey.Seed())
+	if err != nil {
+		return nil, err
+	}
+	t.Message, t.Signature, err = signer.SignHex("transfer farm ", t.FarmID, " on ", t.Network, " from ", t.From, " to ", t.To, " at ", t.Time.Unix())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign the transfer")
+	}
+	t.Status = transferSigned
+	return t, nil
+}
+
+// checkTransferConfirmation checks that the farm name was typed to confirm the transfer
+func checkTransferConfirmation(t *farmTransfer, typed string) error {
+	if strings.TrimSpace(typed) != t.FarmName {
+		return fmt.Errorf("type the farm name %q to confirm the transfer", t.FarmName)
+	}
+	return nil
+}
+
+// Submit validates the signed intent against the explorer record of the
+// owner and transfers the farm. The outcome is recorded in the audit log
+// and the farm is returned as reloaded from the explorer
+func (t *farmTransfer) Submit(expclient *Client, n Network, farm Farm) (Farm, error) {
+	updated, err := t.submit(expclient, farm)
+	t.Status = transferDone
+	if err != nil {
+		t.Status, t.Error = transferFailed, err.Error()
+	}
+	if logErr := appendTransferLog(n, *t); logErr != nil && err == nil {
+		err = errors.Wrap(logErr, "farm transferred but writing the audit log failed")
+	}
+	return updated, err
+}
+
+func (t *farmTransfer) submit(expclient *Client, farm Farm) (Farm, error) {
+	valid, err := expclient.Phonebook.Validate(t.From, t.Message, t.Signature)
+	if err != nil {
+		return farm, errors.Wrap(err, "failed to validate the transfer signature")
+	}
+	if !valid {
+		return farm, fmt.Errorf("the explorer doesn't accept the transfer signature of %d", t.From)
+	}
+
+	update, current, err := newFarmUpdate(expclient, farm, func(f *Farm) {
+		f.ThreebotID = t.To
+	})
+	if err != nil {
+		return current, err
+	}
+	return update.Submit(expclient)
+}
+
+// Decline records in the audit log that the transfer was not confirmed
+func (t *farmTransfer) Decline(n Network, reason string) error {
+	t.Status, t.Error = transferDeclined, reason
+	return appendTransferLog(n, *t)
+}
+
+// appendTransferLog adds t to the audit log of network n
+func appendTransferLog(n Network, t farmTransfer) error {
+	dir, err := networkDir(n)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, transferLogFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// readTransferLog returns the transfers recorded on network n, oldest first
+func readTransferLog(n Network) ([]farmTransfer, error) {
+	dir, err := networkDir(n)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(dir, transferLogFile))
+	if os.IsNotExist(err) {
+		return []farmTransfer{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	transfers := make([]farmTransfer, 0)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var t farmTransfer
+		if err := json.Unmarshal(scanner.Bytes(), &t); err != nil {
+			return transfers, errors.Wrapf(err, "invalid entry on line %d of %s", line, transferLogFile)
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, scanner.Err()
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 
@@ -12,6 +13,7 @@ import (
 // Version History:
 //   1.0.0: seed binary directly encoded
 //   1.1.0: json with key mnemonic and threebot id
+//   1.2.0: 1.1.0 json encrypted with a passphrase (see seedcrypt.go)
 
 // TODO: remove once zos have exposed those variable
 // https://github.com/threefoldtech/zos/blob/0ddc48e01b787893017095f71d5fd97efc42ef1a/pkg/identity/keys.go#L18
@@ -41,16 +43,39 @@ func (u *UserIdentity) Key() KeyPair {
 
 // Load fetch a seed file and initialize key based on mnemonic
 func (u *UserIdentity) Load(path string) error {
-	version, buf, err := ReadFile(path)
+	return u.LoadWithPassphrase(path, "")
+}
+
+// LoadWithPassphrase is like Load but opens encrypted seed files with passphrase.
+// ErrPassphraseRequired is returned if the file is encrypted and passphrase is
+// empty, ThreebotID is still set in that case
+func (u *UserIdentity) LoadWithPassphrase(path, passphrase string) error {
+	data, err := seedStore.Get(path)
 	if err != nil {
 		return err
 	}
+	version, buf, err := ReadBytes(data)
+	if IsNotVersioned(err) {
+		return ErrLegacySeed
+	} else if err != nil {
+		return err
+	}
 
 	if version.Compare(SeedVersion1) == 0 {
-		return fmt.Errorf("seed file too old, please update it using 'tfuser id convert' command")
+		return ErrLegacySeed
 	}
 
-	if version.NE(SeedVersionLatest) {
+	if version.EQ(SeedVersion12) {
+		if passphrase == "" {
+			if e, err := readEncryptedSeed(path); err == nil {
+				u.ThreebotID = e.ThreebotID
+			}
+			return ErrPassphraseRequired
+		}
+		if buf, err = openSeed(buf, passphrase); err != nil {
+			return err
+		}
+	} else if version.NE(SeedVersion11) {
 		return fmt.Errorf("unsupported seed version")
 	}
 
@@ -80,6 +105,12 @@ func (u *UserIdentity) FromMnemonic(mnemonic string) error {
 
 // Save dumps UserIdentity into a versioned file
 func (u *UserIdentity) Save(path string) error {
+	return u.SaveWithPassphrase(path, "")
+}
+
+// SaveWithPassphrase is like Save but encrypts the file with passphrase
+// if it's not empty
+func (u *UserIdentity) SaveWithPassphrase(path, passphrase string) error {
 	var err error
 
 	log.Info().Msg("generating seed mnemonic")
@@ -96,11 +127,24 @@ func (u *UserIdentity) Save(path string) error {
 		return err
 	}
 
-	// Saving json to file
-	log.Info().Str("filename", path).Msg("writing user identity")
-	WriteFile(path, SeedVersion11, buf, 0400)
+	version := SeedVersion11
+	if passphrase != "" {
+		log.Info().Msg("encrypting seed")
+		buf, err = sealSeed(buf, passphrase, u.ThreebotID, hex.EncodeToString(u.key.PublicKey))
+		if err != nil {
+			return err
+		}
+		version = SeedVersion12
+	}
 
-	return nil
+	data, err := WriteBytes(version, buf)
+	if err != nil {
+		return err
+	}
+
+	// Saving json to the secret store
+	log.Info().Str("filename", path).Msg("writing user identity")
+	return seedStore.Set(path, data)
 }
 
 // PrivateKey implements the client.Identity interface
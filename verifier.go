@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zaibon/httpsig"
+)
+
+// signatureHeaders are the headers the client signs and the Verifier requires
+var signatureHeaders = []string{"(created)", "date", "threebot-id"}
+
+// defaultMaxSkew is how far from now the date of a signed request can be
+const defaultMaxSkew = 5 * time.Minute
+
+// ErrInvalidSignature is returned by the Verifier when a request is not
+// signed, or not signed by the 3Bot it claims to come from
+var ErrInvalidSignature = fmt.Errorf("invalid signature")
+
+// KeyLookup returns the public key of the 3Bot with ID id
+type KeyLookup func(id int64) (ed25519.PublicKey, error)
+
+// PhonebookKeys looks up the keys of the users registered in phonebook
+func PhonebookKeys(phonebook Phonebook) KeyLookup {
+	return func(id int64) (ed25519.PublicKey, error) {
+		user, err := phonebook.Get(id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get user %d", id)
+		}
+		return KeyFromHex(user.Pubkey)
+	}
+}
+
+// Verifier authenticates the requests signed by the client of a gofarmer
+// identity, it is the server side of httpClient.sign
+type Verifier struct {
+	// MaxSkew is how far the date of a request can be from now, 0 disables the check
+	MaxSkew time.Duration
+
+	lookup   KeyLookup
+	verifier *httpsig.Verifier
+	now      func() time.Time
+}
+
+// NewVerifier returns a verifier resolving the keys of the signers with lookup
+func NewVerifier(lookup KeyLookup) *Verifier {
+	v := &Verifier{
+		MaxSkew: defaultMaxSkew,
+		lookup:  lookup,
+		now:     time.Now,
+	}
+	v.verifier = httpsig.NewVerifier(httpsig.KeyGetterFunc(v.getKey))
+	v.verifier.SetRequiredHeaders(signatureHeaders)
+	return v
+}
+
+// keyLookupError is a failure of the KeyLookup, as opposed to a bad signature
+type keyLookupError struct {
+	err error
+}
+
+func (e keyLookupError) Error() string {
+	return e.err.Error()
+}
+
+func (v *Verifier) getKey(keyID string) (interface{}, error) {
+	id, err := strconv.ParseInt(keyID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("key id %q is not a 3Bot ID", keyID)
+	}
+	key, err := v.lookup(id)
+	if err != nil {
+		return nil, keyLookupError{err}
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("3Bot %d has no valid public key", id)
+	}
+	return key, nil
+}
+
+// Verify checks the signature of r and returns the 3Bot ID that signed it.
+// Errors not caused by the key lookup have ErrInvalidSignature as cause
+func (v *Verifier) Verify(r *http.Request) (int64, error) {
+	header := r.Header.Get("threebot-id")
+	if header == "" {
+		return 0, errors.Wrap(ErrInvalidSignature, "threebot-id header is missing")
+	}
+	if v.MaxSkew > 0 {
+		date, err := http.ParseTime(r.Header.Get("date"))
+		if err != nil {
+			return 0, errors.Wrap(ErrInvalidSignature, "date header is missing or invalid")
+		}
+		if skew := v.now().Sub(date); skew > v.MaxSkew || -skew > v.MaxSkew {
+			return 0, errors.Wrapf(ErrInvalidSignature, "request date %s is too far from now", date.Format(time.RFC3339))
+		}
+	}
+
+	keyID, err := v.verifier.Verify(r)
+	if lookupErr, ok := err.(keyLookupError); ok {
+		return 0, errors.Wrap(lookupErr.err, "failed to get the key of the signer")
+	} else if err != nil {
+		return 0, errors.Wrap(ErrInvalidSignature, err.Error())
+	}
+	if keyID != header {
+		return 0, errors.Wrapf(ErrInvalidSignature, "request of 3Bot %s is signed by %s", header, keyID)
+	}
+	return strconv.ParseInt(keyID, 10, 64)
+}
+
+type threebotIDKey struct{}
+
+// RequireSignature returns a handler passing the requests signed by a 3Bot
+// to h, the ID of the 3Bot is set in the request context. Other requests are
+// answered with 401 and the error in the explorer format
+func (v *Verifier) RequireSignature(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := v.Verify(r)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), threebotIDKey{}, id)))
+	})
+}
+
+// ThreebotIDFromContext returns the ID of the 3Bot that signed the request
+// passed by RequireSignature
+func ThreebotIDFromContext(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(threebotIDKey{}).(int64)
+	return id, ok
+}
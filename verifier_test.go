@@ -0,0 +1,144 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// signedRequest returns a request signed by ui like the client signs them
+func signedRequest(t *testing.T, ui *UserIdentity) *http.Request {
+	t.Helper()
+	c, err := newHTTPClient("http://explorer.test", ui)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, c.url("farms"), strings.NewReader("{}"))
+	if err := c.sign(r); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func newTestUserIdentity(t *testing.T, id int64) *UserIdentity {
+	t.Helper()
+	k, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewUserIdentity(k, id)
+}
+
+// keysOf looks up the keys of ids
+func keysOf(ids ...*UserIdentity) KeyLookup {
+	return func(id int64) (ed25519.PublicKey, error) {
+		for _, ui := range ids {
+			if ui.ThreebotID == id {
+				return ui.Key().PublicKey, nil
+			}
+		}
+		return nil, fmt.Errorf("user %d not found", id)
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	alice, bob := newTestUserIdentity(t, 1), newTestUserIdentity(t, 2)
+	v := NewVerifier(keysOf(alice, bob))
+
+	id, err := v.Verify(signedRequest(t, alice))
+	if err != nil || id != alice.ThreebotID {
+		t.Fatalf("expected the request to be signed by %d, got %d (%v)", alice.ThreebotID, id, err)
+	}
+
+	cases := map[string]func(r *http.Request){
+		"unsigned":          func(r *http.Request) { r.Header.Del("Authorization") },
+		"no threebot-id":    func(r *http.Request) { r.Header.Del("threebot-id") },
+		"other threebot-id": func(r *http.Request) { r.Header.Set("threebot-id", "2") },
+		"tampered date":     func(r *http.Request) { r.Header.Set("date", time.Now().Add(time.Second).UTC().Format(http.TimeFormat)) },
+		"old date":          func(r *http.Request) { r.Header.Set("date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)) },
+		"tampered signature": func(r *http.Request) {
+			r.Header.Set("Authorization", strings.Replace(r.Header.Get("Authorization"), `keyId="1"`, `keyId="2"`, 1))
+		},
+		"no signed headers": func(r *http.Request) {
+			r.Header.Set("Authorization", strings.Replace(r.Header.Get("Authorization"), `headers="(created) date threebot-id"`, `headers="date"`, 1))
+		},
+	}
+	for name, tamper := range cases {
+		r := signedRequest(t, alice)
+		tamper(r)
+		if _, err := v.Verify(r); errors.Cause(err) != ErrInvalidSignature {
+			t.Errorf("%s: expected an invalid signature, got %v", name, err)
+		}
+	}
+
+	// the key of an unknown user can't be found, which is not a bad signature
+	stranger := newTestUserIdentity(t, 3)
+	if _, err := v.Verify(signedRequest(t, stranger)); err == nil || errors.Cause(err) == ErrInvalidSignature {
+		t.Fatalf("expected a lookup failure, got %v", err)
+	}
+
+	// a user whose key changed is refused
+	impostor := newTestUserIdentity(t, 2)
+	if _, err := v.Verify(signedRequest(t, impostor)); errors.Cause(err) != ErrInvalidSignature {
+		t.Fatalf("expected an invalid signature, got %v", err)
+	}
+}
+
+func TestVerifierMaxSkew(t *testing.T) {
+	alice := newTestUserIdentity(t, 1)
+	v := NewVerifier(keysOf(alice))
+	r := signedRequest(t, alice)
+
+	v.now = func() time.Time { return time.Now().Add(10 * time.Minute) }
+	if _, err := v.Verify(r); errors.Cause(err) != ErrInvalidSignature {
+		t.Fatalf("expected a request from 10 minutes ago to be refused, got %v", err)
+	}
+	v.MaxSkew = 0
+	if _, err := v.Verify(r); err != nil {
+		t.Fatalf("expected the date to be ignored, got %v", err)
+	}
+}
+
+func TestRequireSignature(t *testing.T) {
+	alice := newTestUserIdentity(t, 1)
+	v := NewVerifier(keysOf(alice))
+	handler := v.RequireSignature(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := ThreebotIDFromContext(r.Context())
+		if !ok {
+			t.Error("expected the 3Bot ID in the context")
+		}
+		fmt.Fprint(w, id)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, signedRequest(t, alice))
+	if w.Code != http.StatusOK || w.Body.String() != "1" {
+		t.Fatalf("expected the request of 3Bot 1 to pass, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/farms", nil))
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), `"error"`) {
+		t.Fatalf("expected an unsigned request to be refused, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestPhonebookKeys(t *testing.T) {
+	f := newFakeExplorer(t)
+	ui := newTestIdentity(t, f, "farmer")
+	v := NewVerifier(PhonebookKeys(f.client(t, nil).Phonebook))
+
+	id, err := v.Verify(signedRequest(t, ui))
+	if err != nil || id != ui.ThreebotID {
+		t.Fatalf("expected the request to be signed by %d, got %d (%v)", ui.ThreebotID, id, err)
+	}
+	if _, err := v.Verify(signedRequest(t, newTestUserIdentity(t, 42))); err == nil {
+		t.Fatal("expected an unknown user to be refused")
+	}
+}
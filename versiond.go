@@ -108,6 +108,11 @@ func ReadFile(path string) (Version, []byte, error) {
 		return MustParse("0.0.0"), nil, err
 	}
 
+	return ReadBytes(all)
+}
+
+// ReadBytes splits versioned data into its version and content
+func ReadBytes(all []byte) (Version, []byte, error) {
 	buf := bytes.NewBuffer(all)
 	reader, err := NewReader(buf)
 	if err != nil {
@@ -134,6 +139,20 @@ func WriteFile(filename string, version Version, data []byte, perm os.FileMode)
 	return err
 }
 
+// WriteBytes returns data marked with the provided version
+func WriteBytes(version Version, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, version)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
 // Version type
 type Version = semver.Version
 
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// walletAssets are the assets farms usually get paid in, any other stellar
+// asset code can be used too
+var walletAssets = []string{"TFT", "FreeTFT", "TFTA"}
+
+// isAssetCode matches stellar asset codes, 1 to 12 alphanumeric characters
+var isAssetCode = regexp.MustCompile(`^[A-Za-z0-9]{1,12}$`).MatchString
+
+// String returns the asset and the address of the wallet
+func (w WalletAddress) String() string {
+	return fmt.Sprintf("%s: %s", w.Asset, w.Address)
+}
+
+// validateWalletAddress checks the asset code and the stellar address of w
+func validateWalletAddress(w WalletAddress) error {
+	if !isAssetCode(w.Asset) {
+		return fmt.Errorf("asset %q should be 1 to 12 letters or digits", w.Asset)
+	}
+	if err := validateStellarAddress(w.Address); err != nil {
+		return fmt.Errorf("%s wallet: %w", w.Asset, err)
+	}
+	return nil
+}
+
+// validateWalletAddresses checks every wallet and that each asset has a single address
+func validateWalletAddresses(addresses []WalletAddress) error {
+	seen := make(map[string]bool, len(addresses))
+	for _, w := range addresses {
+		if err := validateWalletAddress(w); err != nil {
+			return err
+		}
+		if seen[w.Asset] {
+			return fmt.Errorf("asset %s has more than one address", w.Asset)
+		}
+		seen[w.Asset] = true
+	}
+	return nil
+}
+
+// removeWalletAddress returns addresses without the address of asset
+func removeWalletAddress(addresses []WalletAddress, asset string) []WalletAddress {
+	updated := make([]WalletAddress, 0, len(addresses))
+	for _, x := range addresses {
+		if x.Asset != asset {
+			updated = append(updated, x)
+		}
+	}
+	return updated
+}
+
+// walletFlag collects ASSET:ADDRESS pairs given on the command line
+type walletFlag []WalletAddress
+
+func (f *walletFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(*f))
+	for _, w := range *f {
+		pairs = append(pairs, w.Asset+":"+w.Address)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f *walletFlag) Set(value string) error {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("expected ASSET:ADDRESS")
+	}
+	w := WalletAddress{Asset: strings.TrimSpace(parts[0]), Address: strings.TrimSpace(parts[1])}
+	if err := validateWalletAddress(w); err != nil {
+		return err
+	}
+	*f = append(*f, w)
+	return nil
+}